@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// Structs
+
+type BaccaratCoup struct {
+	PlayerCards []string `json:"playerCards"`
+	BankerCards []string `json:"bankerCards"`
+	PlayerTotal int      `json:"playerTotal"`
+	BankerTotal int      `json:"bankerTotal"`
+	Winner      string   `json:"winner"`
+	Natural     bool     `json:"natural"`
+}
+
+type BaccaratRules struct {
+	Variant string `json:"variant"`
+	TiePays int    `json:"tiePays"`
+}
+
+type BaccaratBetResult struct {
+	Bet        string  `json:"bet"`
+	Amount     float64 `json:"amount"`
+	Outcome    string  `json:"outcome"`
+	Payout     float64 `json:"payout"`
+	Commission float64 `json:"commission"`
+}
+
+type BaccaratSettlement struct {
+	Bets       []BaccaratBetResult `json:"bets"`
+	Commission float64             `json:"commission"`
+	NetResult  float64             `json:"netResult"`
+}
+
+type BaccaratCoupBody struct {
+	ShoeID string             `json:"shoeId"`
+	Bets   map[string]float64 `json:"bets"`
+	Rules  *BaccaratRules     `json:"rules"`
+}
+
+type BaccaratCoupResponseBody struct {
+	BaccaratCoup
+	Settlement *BaccaratSettlement `json:"settlement,omitempty"`
+	CardsLeft  int                 `json:"cardsLeft"`
+	Reshuffled bool                `json:"reshuffled"`
+}
+
+// Package Variables
+
+const BaccaratPlayer = "PLAYER"
+const BaccaratBanker = "BANKER"
+const BaccaratTie = "TIE"
+
+const BaccaratVariantStandard = "STANDARD"
+const BaccaratVariantEZ = "EZ"
+const BaccaratVariantNoCommission = "NO_COMMISSION"
+
+const BaccaratCommission = 0.05
+
+const BetOutcomeWin = "WIN"
+const BetOutcomeLose = "LOSE"
+const BetOutcomePush = "PUSH"
+
+// Functions
+
+func BankerDraws(bankerTotal int, playerThirdCard string) bool {
+	// A Player who stood leaves the Banker drawing like the Player would
+	if playerThirdCard == "" {
+		return bankerTotal <= 5
+	}
+
+	thirdCardValue := CalculateBaccaratValueForCard(playerThirdCard)
+	switch bankerTotal {
+	case 0, 1, 2:
+		return true
+	case 3:
+		return thirdCardValue != 8
+	case 4:
+		return thirdCardValue >= 2 && thirdCardValue <= 7
+	case 5:
+		return thirdCardValue >= 4 && thirdCardValue <= 7
+	case 6:
+		return thirdCardValue == 6 || thirdCardValue == 7
+	}
+	return false
+}
+
+func PlayBaccaratCoup(shoe *Shoe) BaccaratCoup {
+	var playerCards, bankerCards []string
+	draw := func() string {
+		card := shoe.DrawCard()
+		return card.String()
+	}
+
+	playerCards = append(playerCards, draw())
+	bankerCards = append(bankerCards, draw())
+	playerCards = append(playerCards, draw())
+	bankerCards = append(bankerCards, draw())
+
+	if !CalculateIsNatural(playerCards) && !CalculateIsNatural(bankerCards) {
+		playerThirdCard := ""
+		if CalculateBaccaratValueForCards(playerCards) <= 5 {
+			playerThirdCard = draw()
+			playerCards = append(playerCards, playerThirdCard)
+		}
+		if BankerDraws(CalculateBaccaratValueForCards(bankerCards), playerThirdCard) {
+			bankerCards = append(bankerCards, draw())
+		}
+	}
+
+	return NewBaccaratCoup(playerCards, bankerCards)
+}
+
+func NewBaccaratCoup(playerCards []string, bankerCards []string) BaccaratCoup {
+	coup := BaccaratCoup{
+		PlayerCards: playerCards,
+		BankerCards: bankerCards,
+		PlayerTotal: CalculateBaccaratValueForCards(playerCards),
+		BankerTotal: CalculateBaccaratValueForCards(bankerCards),
+		Natural:     CalculateIsNatural(playerCards[:2]) || CalculateIsNatural(bankerCards[:2]),
+	}
+
+	switch {
+	case coup.PlayerTotal > coup.BankerTotal:
+		coup.Winner = BaccaratPlayer
+	case coup.BankerTotal > coup.PlayerTotal:
+		coup.Winner = BaccaratBanker
+	default:
+		coup.Winner = BaccaratTie
+	}
+	return coup
+}
+
+func DefaultBaccaratRules() BaccaratRules {
+	return BaccaratRules{Variant: BaccaratVariantStandard, TiePays: 8}
+}
+
+func (rules *BaccaratRules) UnmarshalJSON(data []byte) error {
+	type plainRules BaccaratRules
+	decodedRules := plainRules(DefaultBaccaratRules())
+	if err := json.Unmarshal(data, &decodedRules); err != nil {
+		return err
+	}
+	*rules = BaccaratRules(decodedRules)
+	return nil
+}
+
+func ValidateBaccaratRules(rules BaccaratRules) error {
+	switch rules.Variant {
+	case BaccaratVariantStandard, BaccaratVariantEZ, BaccaratVariantNoCommission:
+	default:
+		return fmt.Errorf("variant must be %s, %s or %s", BaccaratVariantStandard, BaccaratVariantEZ, BaccaratVariantNoCommission)
+	}
+	if rules.TiePays != 8 && rules.TiePays != 9 {
+		return fmt.Errorf("tie must pay 8 or 9")
+	}
+	return nil
+}
+
+func ValidateBaccaratBets(bets map[string]float64) error {
+	for name, amount := range bets {
+		_, isSideBet := BaccaratSideBetPayouts[name]
+		if name != BaccaratPlayer && name != BaccaratBanker && name != BaccaratTie && !isSideBet {
+			return fmt.Errorf("unknown bet %q", name)
+		}
+		if amount <= 0 {
+			return fmt.Errorf("bet %s must be positive", name)
+		}
+	}
+	return nil
+}
+
+func SettleBaccaratBet(coup BaccaratCoup, bet string, amount float64, rules BaccaratRules) BaccaratBetResult {
+	result := BaccaratBetResult{Bet: bet, Amount: amount, Outcome: BetOutcomeLose, Payout: -amount}
+	win := func(odds float64) {
+		result.Outcome = BetOutcomeWin
+		result.Payout = amount * odds
+	}
+	push := func() {
+		result.Outcome = BetOutcomePush
+		result.Payout = 0
+	}
+
+	switch bet {
+	case BaccaratPlayer:
+		if coup.Winner == BaccaratPlayer {
+			win(1)
+		} else if coup.Winner == BaccaratTie {
+			push()
+		}
+	case BaccaratBanker:
+		if coup.Winner == BaccaratTie {
+			push()
+		} else if coup.Winner == BaccaratBanker {
+			switch {
+			case rules.Variant == BaccaratVariantEZ && coup.BankerTotal == 7 && len(coup.BankerCards) == 3:
+				push()
+			case rules.Variant == BaccaratVariantEZ:
+				win(1)
+			case rules.Variant == BaccaratVariantNoCommission && coup.BankerTotal == 6:
+				win(0.5)
+			case rules.Variant == BaccaratVariantNoCommission:
+				win(1)
+			default:
+				// The house keeps its commission out of the Banker win
+				result.Commission = amount * BaccaratCommission
+				win(1 - BaccaratCommission)
+			}
+		}
+	case BaccaratTie:
+		if coup.Winner == BaccaratTie {
+			win(float64(rules.TiePays))
+		}
+	default:
+		return EvaluateBaccaratSideBet(coup, bet, amount)
+	}
+
+	return result
+}
+
+func SettleBaccaratBets(coup BaccaratCoup, bets map[string]float64, rules BaccaratRules) BaccaratSettlement {
+	var names []string
+	for name := range bets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	settlement := BaccaratSettlement{Bets: []BaccaratBetResult{}}
+	for _, name := range names {
+		result := SettleBaccaratBet(coup, name, bets[name], rules)
+		settlement.Bets = append(settlement.Bets, result)
+		settlement.Commission += result.Commission
+		settlement.NetResult += result.Payout
+	}
+	return settlement
+}
+
+// Handlers
+
+func CreateBaccaratCoupHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var coupBody BaccaratCoupBody
+	err := json.NewDecoder(r.Body).Decode(&coupBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	rules := DefaultBaccaratRules()
+	if coupBody.Rules != nil {
+		rules = *coupBody.Rules
+	}
+	rulesErr := ValidateBaccaratRules(rules)
+	if rulesErr != nil {
+		http.Error(w, rulesErr.Error(), http.StatusBadRequest)
+		return
+	}
+	betsErr := ValidateBaccaratBets(coupBody.Bets)
+	if betsErr != nil {
+		http.Error(w, betsErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	shoe, found := FindShoe(coupBody.ShoeID)
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	coup := PlayBaccaratCoup(shoe)
+	RecordBaccaratCoup(shoe, coup)
+	reshuffled := shoe.EndRound()
+
+	coupResponseBody := BaccaratCoupResponseBody{BaccaratCoup: coup, CardsLeft: shoe.CardsLeft(), Reshuffled: reshuffled}
+	if len(coupBody.Bets) > 0 {
+		settlement := SettleBaccaratBets(coup, coupBody.Bets, rules)
+		coupResponseBody.Settlement = &settlement
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(coupResponseBody)
+}
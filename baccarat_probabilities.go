@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// Structs
+
+type BaccaratProbabilitiesBody struct {
+	ShoeID      string         `json:"shoeId"`
+	Composition map[string]int `json:"composition"`
+	Decks       int            `json:"decks"`
+	Rules       *BaccaratRules `json:"rules"`
+}
+
+type BaccaratBetProbability struct {
+	Bet           string  `json:"bet"`
+	Win           float64 `json:"win"`
+	Push          float64 `json:"push"`
+	Lose          float64 `json:"lose"`
+	ExpectedValue float64 `json:"expectedValue"`
+	Edge          float64 `json:"edge"`
+}
+
+type BaccaratProbabilitiesResponseBody struct {
+	CardsLeft int                      `json:"cardsLeft"`
+	Player    float64                  `json:"player"`
+	Banker    float64                  `json:"banker"`
+	Tie       float64                  `json:"tie"`
+	Bets      []BaccaratBetProbability `json:"bets"`
+}
+
+// Every final coup with the same totals and card counts settles every bet but the pairs the same way
+type baccaratOutcome struct {
+	PlayerTotal int
+	BankerTotal int
+	PlayerCards int
+	BankerCards int
+}
+
+// Package Variables
+
+// Indexed by baccarat value
+const baccaratValueRanks = "TA23456789"
+
+const DefaultBaccaratDecks = 8
+
+// Functions
+
+func BaccaratRankCountsFromCards(cards []Card) map[string]int {
+	counts := make(map[string]int)
+	for _, card := range cards {
+		counts[card.RankLabel]++
+	}
+	return counts
+}
+
+func NewBaccaratRankCounts(decks int) map[string]int {
+	counts := make(map[string]int)
+	for _, rank := range ranks {
+		counts[rank.Label] = 4 * decks
+	}
+	return counts
+}
+
+func ValidateBaccaratRankCounts(counts map[string]int) error {
+	for rank, count := range counts {
+		if len(rank) != 1 || CalculateBaccaratValueForCard(rank) < 0 || count < 0 {
+			return fmt.Errorf("invalid composition entry %q: %d", rank, count)
+		}
+	}
+	return nil
+}
+
+func forEachBaccaratDraw(values *[10]int, weight float64, visit func(value int, weight float64)) {
+	total := 0
+	for _, count := range values {
+		total += count
+	}
+	for value, count := range values {
+		if count == 0 {
+			continue
+		}
+		values[value]--
+		visit(value, weight*float64(count)/float64(total))
+		values[value]++
+	}
+}
+
+func CalculateBaccaratOutcomes(counts map[string]int) map[baccaratOutcome]float64 {
+	var values [10]int
+	for rank, count := range counts {
+		values[CalculateBaccaratValueForCard(rank)] += count
+	}
+
+	outcomes := make(map[baccaratOutcome]float64)
+	// Cards come out Player, Banker, Player, Banker, then the third cards as the tableau calls for them
+	forEachBaccaratDraw(&values, 1, func(player1 int, weight float64) {
+		forEachBaccaratDraw(&values, weight, func(banker1 int, weight float64) {
+			forEachBaccaratDraw(&values, weight, func(player2 int, weight float64) {
+				forEachBaccaratDraw(&values, weight, func(banker2 int, weight float64) {
+					playerTotal, bankerTotal := (player1+player2)%10, (banker1+banker2)%10
+
+					switch {
+					case playerTotal >= 8 || bankerTotal >= 8:
+						outcomes[baccaratOutcome{playerTotal, bankerTotal, 2, 2}] += weight
+					case playerTotal <= 5:
+						forEachBaccaratDraw(&values, weight, func(player3 int, weight float64) {
+							finalPlayerTotal := (playerTotal + player3) % 10
+							if !BankerDraws(bankerTotal, string(baccaratValueRanks[player3])) {
+								outcomes[baccaratOutcome{finalPlayerTotal, bankerTotal, 3, 2}] += weight
+								return
+							}
+							forEachBaccaratDraw(&values, weight, func(banker3 int, weight float64) {
+								outcomes[baccaratOutcome{finalPlayerTotal, (bankerTotal + banker3) % 10, 3, 3}] += weight
+							})
+						})
+					case bankerTotal <= 5:
+						forEachBaccaratDraw(&values, weight, func(banker3 int, weight float64) {
+							outcomes[baccaratOutcome{playerTotal, (bankerTotal + banker3) % 10, 2, 3}] += weight
+						})
+					default:
+						outcomes[baccaratOutcome{playerTotal, bankerTotal, 2, 2}] += weight
+					}
+				})
+			})
+		})
+	})
+	return outcomes
+}
+
+func baccaratHandForTotal(total int, cards int) []string {
+	// Picture cards fill out the hand so it reaches the total without forming a pair or a natural it shouldn't have
+	if cards == 3 {
+		return []string{"K", "Q", string(baccaratValueRanks[total])}
+	}
+	return []string{"K", string(baccaratValueRanks[total])}
+}
+
+func (outcome baccaratOutcome) Coup() BaccaratCoup {
+	return NewBaccaratCoup(baccaratHandForTotal(outcome.PlayerTotal, outcome.PlayerCards), baccaratHandForTotal(outcome.BankerTotal, outcome.BankerCards))
+}
+
+func CalculateBaccaratPairProbability(counts map[string]int) float64 {
+	// The two cards of a hand are as likely as any two cards from the shoe to match
+	total, matching := 0, 0
+	for _, count := range counts {
+		total += count
+		matching += count * (count - 1)
+	}
+	if total < 2 {
+		return 0
+	}
+	return float64(matching) / float64(total*(total-1))
+}
+
+func CalculateBaccaratProbabilities(counts map[string]int, rules BaccaratRules) BaccaratProbabilitiesResponseBody {
+	outcomes := CalculateBaccaratOutcomes(counts)
+	pairProbability := CalculateBaccaratPairProbability(counts)
+
+	responseBody := BaccaratProbabilitiesResponseBody{Bets: []BaccaratBetProbability{}}
+	for _, count := range counts {
+		responseBody.CardsLeft += count
+	}
+
+	coups := make(map[baccaratOutcome]BaccaratCoup)
+	for outcome, probability := range outcomes {
+		coup := outcome.Coup()
+		coups[outcome] = coup
+		switch coup.Winner {
+		case BaccaratPlayer:
+			responseBody.Player += probability
+		case BaccaratBanker:
+			responseBody.Banker += probability
+		default:
+			responseBody.Tie += probability
+		}
+	}
+
+	names := []string{BaccaratPlayer, BaccaratBanker, BaccaratTie}
+	for name := range BaccaratSideBetPayouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		betProbability := BaccaratBetProbability{Bet: name}
+		switch name {
+		case SideBetPlayerPair, SideBetBankerPair:
+			betProbability.Win = pairProbability
+			betProbability.Lose = 1 - pairProbability
+			betProbability.ExpectedValue = pairProbability*BaccaratSideBetPayouts[name] - betProbability.Lose
+		default:
+			for outcome, probability := range outcomes {
+				result := SettleBaccaratBet(coups[outcome], name, 1, rules)
+				switch result.Outcome {
+				case BetOutcomeWin:
+					betProbability.Win += probability
+				case BetOutcomePush:
+					betProbability.Push += probability
+				default:
+					betProbability.Lose += probability
+				}
+				betProbability.ExpectedValue += probability * result.Payout
+			}
+		}
+		betProbability.Edge = -betProbability.ExpectedValue
+		responseBody.Bets = append(responseBody.Bets, betProbability)
+	}
+	return responseBody
+}
+
+// Handlers
+
+func GetBaccaratProbabilitiesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var probabilitiesBody BaccaratProbabilitiesBody
+	err := json.NewDecoder(r.Body).Decode(&probabilitiesBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	rules := DefaultBaccaratRules()
+	if probabilitiesBody.Rules != nil {
+		rules = *probabilitiesBody.Rules
+	}
+	rulesErr := ValidateBaccaratRules(rules)
+	if rulesErr != nil {
+		http.Error(w, rulesErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var counts map[string]int
+	switch {
+	case probabilitiesBody.ShoeID != "":
+		mutex.Lock()
+		shoe, found := FindShoe(probabilitiesBody.ShoeID)
+		if found {
+			counts = BaccaratRankCountsFromCards(shoe.RemainingCards())
+		}
+		mutex.Unlock()
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+	case probabilitiesBody.Composition != nil:
+		counts = probabilitiesBody.Composition
+		countsErr := ValidateBaccaratRankCounts(counts)
+		if countsErr != nil {
+			http.Error(w, countsErr.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		decks := probabilitiesBody.Decks
+		if decks == 0 {
+			decks = DefaultBaccaratDecks
+		}
+		if decks < 1 || decks > MaxDecksPerShoe {
+			http.Error(w, fmt.Sprintf("decks must be between 1 and %d", MaxDecksPerShoe), http.StatusBadRequest)
+			return
+		}
+		counts = NewBaccaratRankCounts(decks)
+	}
+
+	cardsLeft := 0
+	for _, count := range counts {
+		cardsLeft += count
+	}
+	// A coup can take up to six cards
+	if cardsLeft < 6 {
+		http.Error(w, "Too few cards left in the composition", http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(CalculateBaccaratProbabilities(counts, rules))
+}
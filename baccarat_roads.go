@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Structs
+
+type BaccaratHistory struct {
+	Shuffle int
+	Coups   []BaccaratCoup
+}
+
+type RoadCell struct {
+	Result     string `json:"result"`
+	Ties       int    `json:"ties,omitempty"`
+	PlayerPair bool   `json:"playerPair,omitempty"`
+	BankerPair bool   `json:"bankerPair,omitempty"`
+	Natural    bool   `json:"natural,omitempty"`
+}
+
+// Columns of RoadRows cells, top to bottom, with nil for empty cells
+type RoadGrid [][]*RoadCell
+
+type BaccaratRoadsResponseBody struct {
+	Shuffle      int      `json:"shuffle"`
+	Coups        int      `json:"coups"`
+	BeadPlate    RoadGrid `json:"beadPlate"`
+	BigRoad      RoadGrid `json:"bigRoad"`
+	BigEyeBoy    RoadGrid `json:"bigEyeBoy"`
+	SmallRoad    RoadGrid `json:"smallRoad"`
+	CockroachPig RoadGrid `json:"cockroachPig"`
+}
+
+// Package Variables
+
+var BaccaratHistories = make(map[string]*BaccaratHistory)
+
+const RoadRows = 6
+
+const RoadRed = "RED"
+const RoadBlue = "BLUE"
+
+// Functions
+
+func FindBaccaratHistory(shoe *Shoe) *BaccaratHistory {
+	history, found := BaccaratHistories[shoe.ID]
+	if !found {
+		history = &BaccaratHistory{Shuffle: len(shoe.Shuffles)}
+		BaccaratHistories[shoe.ID] = history
+	}
+	return history
+}
+
+func RecordBaccaratCoup(shoe *Shoe, coup BaccaratCoup) {
+	// Every shuffle starts a fresh scoreboard, but the finished shoe's roads stay up until the next coup is dealt
+	history := FindBaccaratHistory(shoe)
+	if history.Shuffle != len(shoe.Shuffles) {
+		history.Shuffle = len(shoe.Shuffles)
+		history.Coups = nil
+	}
+	history.Coups = append(history.Coups, coup)
+}
+
+func NewRoadCell(coup BaccaratCoup) *RoadCell {
+	return &RoadCell{
+		Result:     coup.Winner,
+		PlayerPair: IsBaccaratPair(coup.PlayerCards),
+		BankerPair: IsBaccaratPair(coup.BankerCards),
+		Natural:    coup.Natural,
+	}
+}
+
+func (grid *RoadGrid) cell(column int, row int) *RoadCell {
+	if column >= len(*grid) {
+		return nil
+	}
+	return (*grid)[column][row]
+}
+
+func (grid *RoadGrid) place(column int, row int, cell *RoadCell) {
+	for column >= len(*grid) {
+		*grid = append(*grid, make([]*RoadCell, RoadRows))
+	}
+	(*grid)[column][row] = cell
+}
+
+func LayoutRoad(streaks [][]*RoadCell) RoadGrid {
+	grid := RoadGrid{}
+	startColumn := -1
+	for _, streak := range streaks {
+		startColumn++
+		for grid.cell(startColumn, 0) != nil {
+			startColumn++
+		}
+
+		column, row := startColumn, 0
+		turned := false
+		for i, cell := range streak {
+			if i > 0 {
+				// A streak runs down until it hits the bottom or another streak, then tails off to the right
+				if !turned && row+1 < RoadRows && grid.cell(column, row+1) == nil {
+					row++
+				} else {
+					turned = true
+					column++
+				}
+			}
+			grid.place(column, row, cell)
+		}
+	}
+	return grid
+}
+
+func BeadPlate(coups []BaccaratCoup) RoadGrid {
+	grid := RoadGrid{}
+	for i, coup := range coups {
+		grid.place(i/RoadRows, i%RoadRows, NewRoadCell(coup))
+	}
+	return grid
+}
+
+func BigRoadStreaks(coups []BaccaratCoup) [][]*RoadCell {
+	var streaks [][]*RoadCell
+	var lastCell *RoadCell
+	leadingTies := 0
+
+	for _, coup := range coups {
+		if coup.Winner == BaccaratTie {
+			// Ties are marked on the previous result instead of taking a cell
+			if lastCell == nil {
+				leadingTies++
+			} else {
+				lastCell.Ties++
+			}
+			continue
+		}
+
+		cell := NewRoadCell(coup)
+		if lastCell == nil {
+			cell.Ties = leadingTies
+		}
+		if lastCell != nil && lastCell.Result == cell.Result {
+			streaks[len(streaks)-1] = append(streaks[len(streaks)-1], cell)
+		} else {
+			streaks = append(streaks, []*RoadCell{cell})
+		}
+		lastCell = cell
+	}
+	return streaks
+}
+
+func DerivedRoadStreaks(bigRoadStreaks [][]*RoadCell, offset int) [][]*RoadCell {
+	var streaks [][]*RoadCell
+	add := func(result string) {
+		if len(streaks) > 0 && streaks[len(streaks)-1][0].Result == result {
+			streaks[len(streaks)-1] = append(streaks[len(streaks)-1], &RoadCell{Result: result})
+		} else {
+			streaks = append(streaks, []*RoadCell{{Result: result}})
+		}
+	}
+
+	for column, streak := range bigRoadStreaks {
+		for row := range streak {
+			if column < offset || (column == offset && row == 0) {
+				continue
+			}
+
+			// A new column compares the depth of the two columns before it, otherwise the entry checks
+			// whether the column offset to its left reached the same depth
+			if row == 0 {
+				if len(bigRoadStreaks[column-1]) == len(bigRoadStreaks[column-1-offset]) {
+					add(RoadRed)
+				} else {
+					add(RoadBlue)
+				}
+			} else if len(bigRoadStreaks[column-offset]) == row {
+				add(RoadBlue)
+			} else {
+				add(RoadRed)
+			}
+		}
+	}
+	return streaks
+}
+
+func MakeBaccaratRoadsResponseBody(coups []BaccaratCoup) BaccaratRoadsResponseBody {
+	bigRoadStreaks := BigRoadStreaks(coups)
+	return BaccaratRoadsResponseBody{
+		Coups:        len(coups),
+		BeadPlate:    BeadPlate(coups),
+		BigRoad:      LayoutRoad(bigRoadStreaks),
+		BigEyeBoy:    LayoutRoad(DerivedRoadStreaks(bigRoadStreaks, 1)),
+		SmallRoad:    LayoutRoad(DerivedRoadStreaks(bigRoadStreaks, 2)),
+		CockroachPig: LayoutRoad(DerivedRoadStreaks(bigRoadStreaks, 3)),
+	}
+}
+
+// Handlers
+
+func GetBaccaratRoadsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	shoeID := vars["id"]
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	shoe, found := FindShoe(shoeID)
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	history := FindBaccaratHistory(shoe)
+	responseBody := MakeBaccaratRoadsResponseBody(history.Coups)
+	responseBody.Shuffle = history.Shuffle
+	json.NewEncoder(w).Encode(responseBody)
+}
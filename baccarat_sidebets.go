@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Structs
+
+type BaccaratSideBetsBody struct {
+	PlayerCards []string           `json:"playerCards"`
+	BankerCards []string           `json:"bankerCards"`
+	Bets        map[string]float64 `json:"bets"`
+}
+
+// Package Variables
+
+const SideBetDragon7 = "DRAGON_7"
+const SideBetPanda8 = "PANDA_8"
+const SideBetPlayerPair = "PLAYER_PAIR"
+const SideBetBankerPair = "BANKER_PAIR"
+const SideBetDragonBonusPlayer = "DRAGON_BONUS_PLAYER"
+const SideBetDragonBonusBanker = "DRAGON_BONUS_BANKER"
+
+// Top award for each side bet; Dragon Bonus pays from its own table
+var BaccaratSideBetPayouts = map[string]float64{
+	SideBetDragon7:           40,
+	SideBetPanda8:            25,
+	SideBetPlayerPair:        11,
+	SideBetBankerPair:        11,
+	SideBetDragonBonusPlayer: 30,
+	SideBetDragonBonusBanker: 30,
+}
+
+// Indexed by the winning margin of a non-natural Dragon Bonus hand
+var DragonBonusPaytable = map[int]float64{9: 30, 8: 10, 7: 6, 6: 4, 5: 2, 4: 1}
+
+// Functions
+
+func IsBaccaratPair(cards []string) bool {
+	return len(cards) >= 2 && cards[0][0] == cards[1][0]
+}
+
+func EvaluateDragonBonus(coup BaccaratCoup, side string) (string, float64) {
+	sideCards, otherCards := coup.PlayerCards, coup.BankerCards
+	sideTotal, otherTotal := coup.PlayerTotal, coup.BankerTotal
+	if side == BaccaratBanker {
+		sideCards, otherCards = coup.BankerCards, coup.PlayerCards
+		sideTotal, otherTotal = coup.BankerTotal, coup.PlayerTotal
+	}
+
+	sideNatural := CalculateIsNatural(sideCards[:2])
+	switch {
+	case sideNatural && sideTotal == otherTotal && CalculateIsNatural(otherCards[:2]):
+		return BetOutcomePush, 0
+	case sideNatural && sideTotal > otherTotal:
+		return BetOutcomeWin, 1
+	case sideTotal > otherTotal && DragonBonusPaytable[sideTotal-otherTotal] > 0:
+		return BetOutcomeWin, DragonBonusPaytable[sideTotal-otherTotal]
+	}
+	return BetOutcomeLose, 0
+}
+
+func EvaluateBaccaratSideBet(coup BaccaratCoup, bet string, amount float64) BaccaratBetResult {
+	outcome, odds := BetOutcomeLose, 0.0
+
+	switch bet {
+	case SideBetDragon7:
+		if coup.Winner == BaccaratBanker && coup.BankerTotal == 7 && len(coup.BankerCards) == 3 {
+			outcome, odds = BetOutcomeWin, BaccaratSideBetPayouts[bet]
+		}
+	case SideBetPanda8:
+		if coup.Winner == BaccaratPlayer && coup.PlayerTotal == 8 && len(coup.PlayerCards) == 3 {
+			outcome, odds = BetOutcomeWin, BaccaratSideBetPayouts[bet]
+		}
+	case SideBetPlayerPair:
+		if IsBaccaratPair(coup.PlayerCards) {
+			outcome, odds = BetOutcomeWin, BaccaratSideBetPayouts[bet]
+		}
+	case SideBetBankerPair:
+		if IsBaccaratPair(coup.BankerCards) {
+			outcome, odds = BetOutcomeWin, BaccaratSideBetPayouts[bet]
+		}
+	case SideBetDragonBonusPlayer:
+		outcome, odds = EvaluateDragonBonus(coup, BaccaratPlayer)
+	case SideBetDragonBonusBanker:
+		outcome, odds = EvaluateDragonBonus(coup, BaccaratBanker)
+	}
+
+	result := BaccaratBetResult{Bet: bet, Amount: amount, Outcome: outcome}
+	switch outcome {
+	case BetOutcomeWin:
+		result.Payout = amount * odds
+	case BetOutcomeLose:
+		result.Payout = -amount
+	}
+	return result
+}
+
+func ValidateBaccaratHand(cards []string) error {
+	if len(cards) < 2 || len(cards) > 3 {
+		return fmt.Errorf("a baccarat hand must have two or three cards")
+	}
+	for _, card := range cards {
+		if CalculateBaccaratValueForCard(card) < 0 {
+			return fmt.Errorf("invalid card %q", card)
+		}
+	}
+	return nil
+}
+
+// Handlers
+
+func GetBaccaratSideBetsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var sideBetsBody BaccaratSideBetsBody
+	err := json.NewDecoder(r.Body).Decode(&sideBetsBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	for _, cards := range [][]string{sideBetsBody.PlayerCards, sideBetsBody.BankerCards} {
+		handErr := ValidateBaccaratHand(cards)
+		if handErr != nil {
+			http.Error(w, handErr.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	betsErr := ValidateBaccaratBets(sideBetsBody.Bets)
+	if betsErr != nil {
+		http.Error(w, betsErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	coup := NewBaccaratCoup(sideBetsBody.PlayerCards, sideBetsBody.BankerCards)
+	json.NewEncoder(w).Encode(SettleBaccaratBets(coup, sideBetsBody.Bets, DefaultBaccaratRules()))
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestBaccaratDrawingRules(t *testing.T) {
+	if !BankerDraws(5, "") || BankerDraws(6, "") {
+		t.Errorf("banker expected to draw on 5 and stand on 6 when the player stands")
+	}
+	if BankerDraws(3, "8S") || !BankerDraws(3, "9S") {
+		t.Errorf("banker 3 expected to stand only on a player 8")
+	}
+	if !BankerDraws(4, "2S") || BankerDraws(4, "AS") || BankerDraws(4, "8S") {
+		t.Errorf("banker 4 expected to draw on player 2 through 7")
+	}
+	if !BankerDraws(5, "4S") || BankerDraws(5, "3S") {
+		t.Errorf("banker 5 expected to draw on player 4 through 7")
+	}
+	if !BankerDraws(6, "7S") || BankerDraws(6, "TS") {
+		t.Errorf("banker 6 expected to draw on player 6 and 7")
+	}
+	if BankerDraws(7, "5S") || !BankerDraws(2, "8S") {
+		t.Errorf("banker 7 expected to stand and banker 2 to draw")
+	}
+}
+
+func TestBaccaratCoups(t *testing.T) {
+	coup := PlayBaccaratCoup(newSequencedShoe([]string{"9S", "TH", "KD", "7C"}))
+	if !coup.Natural || coup.Winner != BaccaratPlayer || len(coup.PlayerCards) != 2 || len(coup.BankerCards) != 2 {
+		t.Errorf("player natural 9 expected to win without draws")
+	}
+
+	// Player 4 draws a 5, banker 5 draws against it
+	coup = PlayBaccaratCoup(newSequencedShoe([]string{"2S", "3H", "2D", "2C", "5S", "4D"}))
+	if coup.Natural || len(coup.PlayerCards) != 3 || len(coup.BankerCards) != 3 {
+		t.Errorf("player and banker expected to draw")
+	}
+	if coup.PlayerTotal != 9 || coup.BankerTotal != 9 || coup.Winner != BaccaratTie {
+		t.Errorf("9 against 9 expected to tie")
+	}
+
+	// Player 6 stands, banker 3 draws
+	coup = PlayBaccaratCoup(newSequencedShoe([]string{"3S", "KH", "3D", "3C", "4S"}))
+	if len(coup.PlayerCards) != 2 || len(coup.BankerCards) != 3 || coup.Winner != BaccaratBanker {
+		t.Errorf("banker expected to draw to 7 and beat player 6")
+	}
+
+	// Player draws an 8, banker 3 stands
+	coup = PlayBaccaratCoup(newSequencedShoe([]string{"TS", "KH", "2D", "3C", "8S"}))
+	if len(coup.PlayerCards) != 3 || len(coup.BankerCards) != 2 || coup.PlayerTotal != 0 || coup.Winner != BaccaratBanker {
+		t.Errorf("banker 3 expected to stand on a player third card 8")
+	}
+}
+
+func TestBaccaratSettlement(t *testing.T) {
+	standard := DefaultBaccaratRules()
+	ez := BaccaratRules{Variant: BaccaratVariantEZ, TiePays: 9}
+	noCommission := BaccaratRules{Variant: BaccaratVariantNoCommission, TiePays: 8}
+
+	bankerSeven := BaccaratCoup{BankerCards: []string{"2S", "2H", "3D"}, BankerTotal: 7, PlayerTotal: 5, Winner: BaccaratBanker}
+	settlement := SettleBaccaratBets(bankerSeven, map[string]float64{BaccaratBanker: 100, BaccaratPlayer: 50}, standard)
+	if settlement.NetResult != 95-50 || settlement.Commission != 5 {
+		t.Errorf("banker win expected to pay 0.95 with 5 commission")
+	}
+	if SettleBaccaratBet(bankerSeven, BaccaratBanker, 100, ez).Outcome != BetOutcomePush {
+		t.Errorf("ez banker three-card 7 expected to push")
+	}
+	if SettleBaccaratBet(bankerSeven, BaccaratBanker, 100, noCommission).Payout != 100 {
+		t.Errorf("no-commission banker 7 expected to pay even money")
+	}
+
+	bankerSix := BaccaratCoup{BankerCards: []string{"2S", "4H"}, BankerTotal: 6, PlayerTotal: 5, Winner: BaccaratBanker}
+	if SettleBaccaratBet(bankerSix, BaccaratBanker, 100, noCommission).Payout != 50 {
+		t.Errorf("no-commission banker 6 expected to pay half")
+	}
+	if result := SettleBaccaratBet(bankerSix, BaccaratBanker, 100, ez); result.Payout != 100 || result.Commission != 0 {
+		t.Errorf("ez banker win expected to pay even money without commission")
+	}
+
+	tie := BaccaratCoup{PlayerTotal: 4, BankerTotal: 4, Winner: BaccaratTie}
+	settlement = SettleBaccaratBets(tie, map[string]float64{BaccaratBanker: 100, BaccaratPlayer: 100, BaccaratTie: 10}, standard)
+	if settlement.NetResult != 80 || settlement.Bets[0].Outcome != BetOutcomePush {
+		t.Errorf("tie expected to pay 8:1 and push player and banker")
+	}
+	if SettleBaccaratBet(tie, BaccaratTie, 10, ez).Payout != 90 {
+		t.Errorf("tie expected to pay 9:1")
+	}
+
+	if ValidateBaccaratRules(BaccaratRules{Variant: BaccaratVariantStandard, TiePays: 7}) == nil {
+		t.Errorf("tie paying 7 expected to be rejected")
+	}
+	if ValidateBaccaratBets(map[string]float64{"DRAGON": 5}) == nil {
+		t.Errorf("unknown bet expected to be rejected")
+	}
+}
+
+func TestBaccaratSideBets(t *testing.T) {
+	dragon := NewBaccaratCoup([]string{"TS", "6H"}, []string{"2S", "2H", "3D"})
+	panda := NewBaccaratCoup([]string{"3S", "3H", "2D"}, []string{"4S", "3C"})
+	naturalWin := NewBaccaratCoup([]string{"4S", "4H"}, []string{"7S", "KH"})
+	naturalTie := NewBaccaratCoup([]string{"4S", "4H"}, []string{"5S", "3H"})
+	bigMargin := NewBaccaratCoup([]string{"TS", "KH", "9D"}, []string{"TC", "JC", "TD"})
+	smallMargin := NewBaccaratCoup([]string{"TS", "2H", "4D"}, []string{"TC", "JC", "3D"})
+
+	if EvaluateBaccaratSideBet(dragon, SideBetDragon7, 10).Payout != 400 {
+		t.Errorf("banker three-card 7 expected to pay dragon 7 at 40:1")
+	}
+	if EvaluateBaccaratSideBet(panda, SideBetPanda8, 10).Payout != 250 || EvaluateBaccaratSideBet(dragon, SideBetPanda8, 10).Payout != -10 {
+		t.Errorf("player three-card 8 expected to pay panda 8 at 25:1")
+	}
+	if EvaluateBaccaratSideBet(panda, SideBetPlayerPair, 10).Payout != 110 || EvaluateBaccaratSideBet(panda, SideBetBankerPair, 10).Payout != -10 {
+		t.Errorf("player pair expected to pay 11:1")
+	}
+	if EvaluateBaccaratSideBet(dragon, SideBetBankerPair, 10).Payout != 110 {
+		t.Errorf("banker pair expected to pay 11:1")
+	}
+
+	if EvaluateBaccaratSideBet(naturalWin, SideBetDragonBonusPlayer, 10).Payout != 10 {
+		t.Errorf("natural win expected to pay dragon bonus 1:1")
+	}
+	if EvaluateBaccaratSideBet(naturalTie, SideBetDragonBonusPlayer, 10).Outcome != BetOutcomePush {
+		t.Errorf("natural tie expected to push dragon bonus")
+	}
+	if EvaluateBaccaratSideBet(bigMargin, SideBetDragonBonusPlayer, 10).Payout != 300 {
+		t.Errorf("non-natural win by 9 expected to pay dragon bonus 30:1")
+	}
+	if EvaluateBaccaratSideBet(smallMargin, SideBetDragonBonusPlayer, 10).Payout != -10 {
+		t.Errorf("non-natural win by 3 expected to lose dragon bonus")
+	}
+	if EvaluateBaccaratSideBet(dragon, SideBetDragonBonusBanker, 10).Payout != -10 {
+		t.Errorf("non-natural banker win by 1 expected to lose dragon bonus")
+	}
+
+	settlement := SettleBaccaratBets(dragon, map[string]float64{BaccaratBanker: 100, SideBetDragon7: 5}, BaccaratRules{Variant: BaccaratVariantEZ, TiePays: 8})
+	if settlement.NetResult != 200 {
+		t.Errorf("ez banker push and dragon 7 expected to net 200")
+	}
+}
+
+func TestBaccaratRoads(t *testing.T) {
+	player := NewBaccaratCoup([]string{"9S", "TH"}, []string{"8S", "TD"})
+	banker := NewBaccaratCoup([]string{"8S", "TD"}, []string{"9S", "TH"})
+	tie := NewBaccaratCoup([]string{"9S", "TH"}, []string{"9H", "TD"})
+	pairs := NewBaccaratCoup([]string{"4S", "4H"}, []string{"2S", "2H", "3D"})
+
+	alternating := MakeBaccaratRoadsResponseBody([]BaccaratCoup{player, banker, player, banker, player})
+	if len(alternating.BigRoad) != 5 || alternating.BigRoad[4][0].Result != BaccaratPlayer {
+		t.Errorf("alternating results expected to fill five big road columns")
+	}
+	if len(alternating.BigEyeBoy) != 1 || alternating.BigEyeBoy[0][2] == nil || alternating.BigEyeBoy[0][2].Result != RoadRed {
+		t.Errorf("alternating results expected to give three red big eye boy entries")
+	}
+	if len(alternating.SmallRoad) != 1 || alternating.SmallRoad[0][1] == nil || alternating.SmallRoad[0][2] != nil {
+		t.Errorf("alternating results expected to give two small road entries")
+	}
+	if len(alternating.CockroachPig) != 1 || alternating.CockroachPig[0][1] != nil {
+		t.Errorf("alternating results expected to give one cockroach pig entry")
+	}
+
+	streak := MakeBaccaratRoadsResponseBody([]BaccaratCoup{banker, banker, banker, banker, banker, banker, banker, player})
+	if streak.BigRoad[0][5] == nil || streak.BigRoad[1][5] == nil || streak.BigRoad[1][0] == nil || streak.BigRoad[1][0].Result != BaccaratPlayer {
+		t.Errorf("seven bankers expected to tail off to the right along the bottom row")
+	}
+	if len(streak.BeadPlate) != 2 || streak.BeadPlate[1][1].Result != BaccaratPlayer {
+		t.Errorf("bead plate expected to fill six rows per column")
+	}
+
+	tied := MakeBaccaratRoadsResponseBody([]BaccaratCoup{tie, pairs, tie, tie})
+	if len(tied.BigRoad) != 1 || tied.BigRoad[0][1] != nil || tied.BigRoad[0][0].Ties != 3 {
+		t.Errorf("ties expected to be marked on the big road cell")
+	}
+	if !tied.BigRoad[0][0].PlayerPair || !tied.BigRoad[0][0].BankerPair || tied.BigRoad[0][0].Result != BaccaratPlayer {
+		t.Errorf("pairs expected to be marked on the big road cell")
+	}
+	if len(tied.BeadPlate[0]) != RoadRows || tied.BeadPlate[0][3].Result != BaccaratTie {
+		t.Errorf("ties expected to take a bead plate cell")
+	}
+
+	shoe := NewShoeWithSource(1, NewSeededSource(1))
+	shoe.ID = "roads"
+	Shoes[shoe.ID] = shoe
+	defer DeleteShoe(shoe.ID)
+
+	coups := 0
+	for {
+		recorder := httptest.NewRecorder()
+		CreateBaccaratCoupHandler(recorder, httptest.NewRequest("POST", "/baccarat/coups", strings.NewReader(`{"shoeId":"roads"}`)))
+		var coup BaccaratCoupResponseBody
+		json.NewDecoder(recorder.Body).Decode(&coup)
+		coups++
+		if coup.Reshuffled {
+			break
+		}
+	}
+
+	roads := func() BaccaratRoadsResponseBody {
+		recorder := httptest.NewRecorder()
+		GetBaccaratRoadsHandler(recorder, mux.SetURLVars(httptest.NewRequest("GET", "/baccarat/shoes/roads/roads", nil), map[string]string{"id": shoe.ID}))
+		var responseBody BaccaratRoadsResponseBody
+		json.NewDecoder(recorder.Body).Decode(&responseBody)
+		return responseBody
+	}
+	finished := roads()
+	if finished.Coups != coups || finished.Shuffle != 1 {
+		t.Errorf("finished shoe's roads expected to stay up after its last coup")
+	}
+
+	RecordBaccaratCoup(shoe, PlayBaccaratCoup(shoe))
+	next := roads()
+	if next.Coups != 1 || next.Shuffle != 2 {
+		t.Errorf("coup history expected to clear on the first coup of the next shoe")
+	}
+}
+
+func TestBaccaratProbabilities(t *testing.T) {
+	probabilities := CalculateBaccaratProbabilities(NewBaccaratRankCounts(8), DefaultBaccaratRules())
+	if math.Abs(probabilities.Player+probabilities.Banker+probabilities.Tie-1) > 1e-9 {
+		t.Errorf("player, banker and tie expected to sum to 1")
+	}
+	if math.Abs(probabilities.Banker-0.458597) > 1e-5 || math.Abs(probabilities.Player-0.446247) > 1e-5 || math.Abs(probabilities.Tie-0.095156) > 1e-5 {
+		t.Errorf("eight deck probabilities expected to match the published figures, got %f %f %f", probabilities.Banker, probabilities.Player, probabilities.Tie)
+	}
+
+	edges := make(map[string]float64)
+	for _, bet := range probabilities.Bets {
+		edges[bet.Bet] = bet.Edge
+	}
+	if math.Abs(edges[BaccaratBanker]-0.010579) > 1e-5 || math.Abs(edges[BaccaratPlayer]-0.012351) > 1e-5 || math.Abs(edges[BaccaratTie]-0.143596) > 1e-5 {
+		t.Errorf("eight deck edges expected to match the published figures")
+	}
+	if math.Abs(edges[SideBetPlayerPair]-0.103614) > 1e-5 || edges[SideBetPlayerPair] != edges[SideBetBankerPair] {
+		t.Errorf("eight deck pair edge expected to be 10.36%%")
+	}
+	if math.Abs(edges[SideBetDragon7]-0.076113) > 1e-5 || math.Abs(edges[SideBetPanda8]-0.101876) > 1e-5 {
+		t.Errorf("dragon 7 and panda 8 edges expected to match the published figures, got %f %f", edges[SideBetDragon7], edges[SideBetPanda8])
+	}
+
+	counts := NewBaccaratRankCounts(1)
+	for _, rank := range []string{"T", "J", "Q", "K"} {
+		counts[rank] = 0
+	}
+	depleted := CalculateBaccaratProbabilities(counts, DefaultBaccaratRules())
+	if depleted.CardsLeft != 36 || math.Abs(depleted.Player+depleted.Banker+depleted.Tie-1) > 1e-9 || depleted.Banker == probabilities.Banker {
+		t.Errorf("removing the ten-value cards expected to change the odds")
+	}
+	if ValidateBaccaratRankCounts(map[string]int{"X": 4}) == nil || ValidateBaccaratRankCounts(map[string]int{"A": -1}) == nil {
+		t.Errorf("invalid compositions expected to be rejected")
+	}
+}
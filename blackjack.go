@@ -0,0 +1,666 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Structs
+
+type BlackjackRules struct {
+	DealerHitsSoft17 bool   `json:"dealerHitsSoft17"`
+	DoubleAfterSplit bool   `json:"doubleAfterSplit"`
+	ResplitAces      bool   `json:"resplitAces"`
+	Surrender        string `json:"surrender"`
+	BlackjackPayout  string `json:"blackjackPayout"`
+	Decks            int    `json:"decks"`
+	MaxSplits        int    `json:"maxSplits"`
+	DealerPeeks      bool   `json:"dealerPeeks"`
+}
+
+type BlackjackHand struct {
+	Cards       []string `json:"cards"`
+	Bet         float64  `json:"bet"`
+	Doubled     bool     `json:"doubled"`
+	Surrendered bool     `json:"surrendered"`
+	FromSplit   bool     `json:"fromSplit"`
+	Done        bool     `json:"done"`
+	Result      string   `json:"result,omitempty"`
+	Payout      float64  `json:"payout"`
+}
+
+type BlackjackRound struct {
+	ID              string
+	Status          string
+	Rules           BlackjackRules
+	Shoe            *Shoe
+	Peeked          bool
+	PlayerHands     []*BlackjackHand
+	ActiveHand      int
+	DealerCards     []string
+	InsuranceBet    float64
+	InsurancePayout float64
+	EvenMoney       bool
+	InitialCards    []string
+	SideBets        map[string]float64
+	SideBetResults  []SideBetResult
+	NetResult       float64
+	Reshuffled      bool
+}
+
+type BlackjackRoundBody struct {
+	ShoeID   string             `json:"shoeId"`
+	Bet      float64            `json:"bet"`
+	Rules    *BlackjackRules    `json:"rules"`
+	SideBets map[string]float64 `json:"sideBets"`
+}
+
+type BlackjackActionBody struct {
+	Action string `json:"action"`
+}
+
+type InsuranceBody struct {
+	Cards     []string `json:"cards"`
+	UpCard    string   `json:"upCard"`
+	TrueCount *float64 `json:"trueCount"`
+	ShoeID    string   `json:"shoeId"`
+}
+
+type InsuranceResponseBody struct {
+	Decision      string   `json:"decision"`
+	TakeInsurance bool     `json:"takeInsurance"`
+	Basis         string   `json:"basis"`
+	TenDensity    *float64 `json:"tenDensity,omitempty"`
+}
+
+type BlackjackRoundResponseBody struct {
+	ID              string           `json:"id"`
+	ShoeID          string           `json:"shoeId"`
+	Status          string           `json:"status"`
+	Rules           BlackjackRules   `json:"rules"`
+	PlayerHands     []*BlackjackHand `json:"playerHands"`
+	ActiveHand      int              `json:"activeHand"`
+	DealerCards     []string         `json:"dealerCards"`
+	DealerValue     int              `json:"dealerValue"`
+	InsuranceBet    float64          `json:"insuranceBet"`
+	InsurancePayout float64          `json:"insurancePayout"`
+	EvenMoney       bool             `json:"evenMoney"`
+	SideBets        []SideBetResult  `json:"sideBets,omitempty"`
+	NetResult       float64          `json:"netResult"`
+	Reshuffled      bool             `json:"reshuffled"`
+}
+
+// Package Variables
+
+var BlackjackRounds = make(map[string]*BlackjackRound)
+
+const RoundStatusInsurance = "INSURANCE"
+const RoundStatusPlayerTurn = "PLAYER_TURN"
+const RoundStatusSettled = "SETTLED"
+
+const SurrenderNone = "NONE"
+const SurrenderLate = "LATE"
+const SurrenderEarly = "EARLY"
+
+var BlackjackPayouts = map[string]float64{"3:2": 1.5, "6:5": 1.2}
+
+const InsuranceBasisBasic = "BASIC"
+const InsuranceBasisTrueCount = "TRUE_COUNT"
+const InsuranceBasisComposition = "COMPOSITION"
+
+// Functions
+
+func DefaultBlackjackRules() BlackjackRules {
+	return BlackjackRules{
+		DealerHitsSoft17: false,
+		DoubleAfterSplit: true,
+		ResplitAces:      false,
+		Surrender:        SurrenderLate,
+		BlackjackPayout:  "3:2",
+		Decks:            6,
+		MaxSplits:        3,
+		DealerPeeks:      true,
+	}
+}
+
+func LegacyStrategyRules() BlackjackRules {
+	// The original strategy chart never surrenders
+	rules := DefaultBlackjackRules()
+	rules.Surrender = SurrenderNone
+	return rules
+}
+
+func (rules *BlackjackRules) UnmarshalJSON(data []byte) error {
+	// Rules left out of the payload keep their default values
+	type plainRules BlackjackRules
+	decodedRules := plainRules(DefaultBlackjackRules())
+	if err := json.Unmarshal(data, &decodedRules); err != nil {
+		return err
+	}
+	*rules = BlackjackRules(decodedRules)
+	return nil
+}
+
+func ValidateBlackjackRules(rules BlackjackRules) error {
+	switch rules.Surrender {
+	case SurrenderNone, SurrenderLate, SurrenderEarly:
+	default:
+		return fmt.Errorf("surrender must be %s, %s or %s", SurrenderNone, SurrenderLate, SurrenderEarly)
+	}
+	if _, found := BlackjackPayouts[rules.BlackjackPayout]; !found {
+		return fmt.Errorf("blackjack payout must be 3:2 or 6:5")
+	}
+	if rules.Decks < 1 || rules.Decks > MaxDecksPerShoe {
+		return fmt.Errorf("decks must be between 1 and %d", MaxDecksPerShoe)
+	}
+	if rules.MaxSplits < 0 {
+		return fmt.Errorf("max splits cannot be negative")
+	}
+	return nil
+}
+
+func NewBlackjackRound(shoe *Shoe, bet float64, rules BlackjackRules) *BlackjackRound {
+	return NewBlackjackRoundWithSideBets(shoe, bet, rules, nil)
+}
+
+func NewBlackjackRoundWithSideBets(shoe *Shoe, bet float64, rules BlackjackRules, sideBets map[string]float64) *BlackjackRound {
+	rules.Decks = shoe.NumDecks
+	round := &BlackjackRound{
+		Status:      RoundStatusPlayerTurn,
+		Rules:       rules,
+		Shoe:        shoe,
+		PlayerHands: []*BlackjackHand{{Bet: bet}},
+		SideBets:    sideBets,
+	}
+
+	playerHand := round.PlayerHands[0]
+	playerHand.Cards = append(playerHand.Cards, round.DrawCard())
+	round.DealerCards = append(round.DealerCards, round.DrawCard())
+	playerHand.Cards = append(playerHand.Cards, round.DrawCard())
+	round.DealerCards = append(round.DealerCards, round.DrawCard())
+	round.InitialCards = append([]string{}, playerHand.Cards...)
+
+	// An ace up card offers insurance before anything else happens
+	if CalculateBlackjackValueForCard(round.DealerCards[0]) == 11 {
+		round.Status = RoundStatusInsurance
+		return round
+	}
+
+	round.StartPlay()
+	return round
+}
+
+func (round *BlackjackRound) StartPlay() {
+	round.Status = RoundStatusPlayerTurn
+	playerHand := round.PlayerHands[0]
+	playerBlackjack := playerHand.IsBlackjack()
+
+	switch {
+	case !round.Rules.DealerPeeks && playerBlackjack:
+		// Without a hole card check a player blackjack is settled against the dealer's second card straight away
+		playerHand.Done = true
+		round.Settle()
+	case round.Rules.DealerPeeks && (round.Rules.Surrender != SurrenderEarly || playerBlackjack):
+		// Early surrender is offered before the dealer checks for blackjack
+		round.Peek()
+	}
+}
+
+func (round *BlackjackRound) ResolveInsurance(action string) error {
+	playerHand := round.PlayerHands[0]
+
+	switch action {
+	case "INSURANCE":
+		round.InsuranceBet = playerHand.Bet / 2
+	case "EVEN_MONEY":
+		if !playerHand.IsBlackjack() {
+			return fmt.Errorf("even money is only offered on a blackjack")
+		}
+		round.EvenMoney = true
+		playerHand.Done = true
+		round.Settle()
+		return nil
+	case "DECLINE_INSURANCE":
+	default:
+		return fmt.Errorf("insurance must be taken or declined first")
+	}
+
+	round.StartPlay()
+	return nil
+}
+
+func (round *BlackjackRound) Peek() bool {
+	round.Peeked = true
+
+	// A dealer or player blackjack ends the round before any decisions
+	playerHand := round.PlayerHands[0]
+	if CalculateIsBlackjack(round.DealerCards) || playerHand.IsBlackjack() {
+		playerHand.Done = true
+		round.Settle()
+		return true
+	}
+	return false
+}
+
+func (round *BlackjackRound) DrawCard() string {
+	card := round.Shoe.DrawCard()
+	return card.String()
+}
+
+func (round *BlackjackRound) Act(action string) error {
+	if round.Status == RoundStatusInsurance {
+		return round.ResolveInsurance(action)
+	}
+	if round.Status != RoundStatusPlayerTurn {
+		return fmt.Errorf("round is not waiting for a player action")
+	}
+
+	if round.Rules.DealerPeeks && !round.Peeked && action != "SURRENDER" && round.Peek() {
+		return nil
+	}
+
+	hand := round.PlayerHands[round.ActiveHand]
+	isInitialHand := len(hand.Cards) == 2 && len(round.PlayerHands) == 1
+
+	switch action {
+	case "HIT":
+		if hand.IsSplitAces() {
+			return fmt.Errorf("split aces only receive one card")
+		}
+		hand.Cards = append(hand.Cards, round.DrawCard())
+		if CalculateBlackjackValueForCards(hand.Cards) >= 21 {
+			hand.Done = true
+		}
+	case "STAND":
+		hand.Done = true
+	case "DOUBLE":
+		if len(hand.Cards) != 2 {
+			return fmt.Errorf("doubling is only allowed on the first two cards of a hand")
+		}
+		if hand.IsSplitAces() {
+			return fmt.Errorf("split aces only receive one card")
+		}
+		if len(round.PlayerHands) > 1 && !round.Rules.DoubleAfterSplit {
+			return fmt.Errorf("doubling after a split is not allowed")
+		}
+		hand.Bet *= 2
+		hand.Doubled = true
+		hand.Cards = append(hand.Cards, round.DrawCard())
+		hand.Done = true
+	case "SPLIT":
+		if err := round.CanSplit(); err != nil {
+			return err
+		}
+		round.Split()
+	case "SURRENDER":
+		if round.Rules.Surrender == SurrenderNone {
+			return fmt.Errorf("surrender is not allowed")
+		}
+		if !isInitialHand {
+			return fmt.Errorf("surrender is only allowed on the initial two cards")
+		}
+		hand.Surrendered = true
+		hand.Done = true
+	default:
+		return fmt.Errorf("unknown action %q", action)
+	}
+
+	round.Advance()
+	return nil
+}
+
+func (hand *BlackjackHand) IsBlackjack() bool {
+	// 21 on a split hand is only worth its total
+	return !hand.FromSplit && CalculateIsBlackjack(hand.Cards)
+}
+
+func (hand *BlackjackHand) IsSplitAces() bool {
+	return hand.FromSplit && hand.Cards[0][0] == 'A'
+}
+
+func (round *BlackjackRound) CanSplit() error {
+	hand := round.PlayerHands[round.ActiveHand]
+	if len(hand.Cards) != 2 || hand.Cards[0][0] != hand.Cards[1][0] {
+		return fmt.Errorf("splitting is only allowed on a pair")
+	}
+	if len(round.PlayerHands)-1 >= round.Rules.MaxSplits {
+		return fmt.Errorf("no more than %d splits are allowed", round.Rules.MaxSplits)
+	}
+	if hand.IsSplitAces() && !round.Rules.ResplitAces {
+		return fmt.Errorf("split aces cannot be resplit")
+	}
+	return nil
+}
+
+func (round *BlackjackRound) Split() {
+	handIndex := round.ActiveHand
+	hand := round.PlayerHands[handIndex]
+	splitHand := &BlackjackHand{Cards: []string{hand.Cards[1]}, Bet: hand.Bet, FromSplit: true}
+	hand.Cards = hand.Cards[:1]
+	hand.FromSplit = true
+
+	// The new hand waits right behind this one and gets its second card when its turn comes
+	playerHands := append([]*BlackjackHand{}, round.PlayerHands[:handIndex+1]...)
+	playerHands = append(playerHands, splitHand)
+	round.PlayerHands = append(playerHands, round.PlayerHands[handIndex+1:]...)
+
+	round.DealSplitCard(hand)
+}
+
+func (round *BlackjackRound) DealSplitCard(hand *BlackjackHand) {
+	hand.Cards = append(hand.Cards, round.DrawCard())
+
+	// Split aces get a single card unless they can be split again
+	if hand.IsSplitAces() && round.CanSplit() != nil {
+		hand.Done = true
+	}
+	if CalculateBlackjackValueForCards(hand.Cards) == 21 {
+		hand.Done = true
+	}
+}
+
+func (round *BlackjackRound) Advance() {
+	for round.ActiveHand < len(round.PlayerHands) {
+		hand := round.PlayerHands[round.ActiveHand]
+		if len(hand.Cards) == 1 {
+			round.DealSplitCard(hand)
+		}
+		if !hand.Done {
+			return
+		}
+		round.ActiveHand++
+	}
+
+	round.ActiveHand = len(round.PlayerHands) - 1
+	round.PlayDealer()
+	round.Settle()
+}
+
+func (round *BlackjackRound) PlayDealer() {
+	// The dealer only draws if at least one hand is still live
+	for _, hand := range round.PlayerHands {
+		if !hand.Surrendered && CalculateBlackjackValueForCards(hand.Cards) <= 21 {
+			for round.DealerShouldHit() {
+				round.DealerCards = append(round.DealerCards, round.DrawCard())
+			}
+			return
+		}
+	}
+}
+
+func IsSoftTotal(cards []string) bool {
+	// A hand is only soft while an ace can still count as 11, which CalculateIsSoft doesn't check for hands like T-6-A
+	hardValue, hasAce := 0, false
+	for _, card := range cards {
+		if card[0] == 'A' {
+			hasAce = true
+			hardValue++
+		} else {
+			hardValue += CalculateBlackjackValueForCard(card)
+		}
+	}
+	return hasAce && hardValue+10 <= 21
+}
+
+func (round *BlackjackRound) DealerShouldHit() bool {
+	dealerValue := CalculateBlackjackValueForCards(round.DealerCards)
+	if dealerValue == 17 && round.Rules.DealerHitsSoft17 {
+		return IsSoftTotal(round.DealerCards)
+	}
+	return dealerValue < 17
+}
+
+func (round *BlackjackRound) Settle() {
+	// Buster is paid on the dealer's final hand, so the dealer plays out even when no player hand is left standing
+	if _, found := round.SideBets[SideBetBuster]; found {
+		for round.DealerShouldHit() {
+			round.DealerCards = append(round.DealerCards, round.DrawCard())
+		}
+	}
+
+	dealerValue := CalculateBlackjackValueForCards(round.DealerCards)
+	dealerBlackjack := CalculateIsBlackjack(round.DealerCards)
+
+	round.NetResult = 0
+	for _, hand := range round.PlayerHands {
+		handValue := CalculateBlackjackValueForCards(hand.Cards)
+		playerBlackjack := hand.IsBlackjack()
+
+		// Late surrender only saves half the bet once the dealer is known not to have blackjack
+		lateSurrenderIntoBlackjack := hand.Surrendered && dealerBlackjack && round.Rules.Surrender == SurrenderLate
+
+		switch {
+		case round.EvenMoney:
+			hand.Result = "EVEN_MONEY"
+			hand.Payout = hand.Bet
+		case hand.Surrendered && !lateSurrenderIntoBlackjack:
+			hand.Result = "SURRENDER"
+			hand.Payout = -hand.Bet / 2
+		case playerBlackjack && dealerBlackjack:
+			hand.Result = "PUSH"
+			hand.Payout = 0
+		case playerBlackjack:
+			hand.Result = "BLACKJACK"
+			hand.Payout = hand.Bet * BlackjackPayouts[round.Rules.BlackjackPayout]
+		case dealerBlackjack:
+			hand.Result = "LOSE"
+			hand.Payout = -hand.Bet
+		case handValue > 21:
+			hand.Result = "BUST"
+			hand.Payout = -hand.Bet
+		case dealerValue > 21 || handValue > dealerValue:
+			hand.Result = "WIN"
+			hand.Payout = hand.Bet
+		case handValue < dealerValue:
+			hand.Result = "LOSE"
+			hand.Payout = -hand.Bet
+		default:
+			hand.Result = "PUSH"
+			hand.Payout = 0
+		}
+
+		round.NetResult += hand.Payout
+	}
+
+	// Insurance pays 2:1 when the dealer has blackjack
+	if round.InsuranceBet > 0 {
+		round.InsurancePayout = -round.InsuranceBet
+		if dealerBlackjack {
+			round.InsurancePayout = 2 * round.InsuranceBet
+		}
+		round.NetResult += round.InsurancePayout
+	}
+
+	round.SideBetResults = EvaluateBlackjackSideBets(round.SideBets, round.InitialCards, round.DealerCards)
+	for _, sideBetResult := range round.SideBetResults {
+		round.NetResult += sideBetResult.Payout
+	}
+
+	round.Status = RoundStatusSettled
+	round.Reshuffled = round.Shoe.EndRound()
+}
+
+func CalculateInsuranceDecision(trueCount *float64, composition *Composition) (bool, string) {
+	// Insurance pays 2:1, so it is worth taking once more than a third of the unseen cards are tens
+	if composition != nil && composition.Total() > 0 {
+		return 3*composition[10] > composition.Total(), InsuranceBasisComposition
+	}
+	if trueCount != nil {
+		return *trueCount >= InsuranceIndex, InsuranceBasisTrueCount
+	}
+	return false, InsuranceBasisBasic
+}
+
+func MakeInsuranceResponseBody(cards []string, trueCount *float64, composition *Composition) InsuranceResponseBody {
+	takeInsurance, basis := CalculateInsuranceDecision(trueCount, composition)
+
+	decision := "DECLINE_INSURANCE"
+	if takeInsurance && CalculateIsBlackjack(cards) {
+		decision = "EVEN_MONEY"
+	} else if takeInsurance {
+		decision = "INSURANCE"
+	}
+
+	insuranceResponseBody := InsuranceResponseBody{Decision: decision, TakeInsurance: takeInsurance, Basis: basis}
+	if basis == InsuranceBasisComposition {
+		tenDensity := float64(composition[10]) / float64(composition.Total())
+		insuranceResponseBody.TenDensity = &tenDensity
+	}
+	return insuranceResponseBody
+}
+
+func MakeBlackjackRoundResponseBody(round *BlackjackRound) BlackjackRoundResponseBody {
+	dealerCards := round.DealerCards
+	if round.Status != RoundStatusSettled {
+		// The hole card stays face down until the player is done
+		dealerCards = dealerCards[:1]
+	}
+
+	return BlackjackRoundResponseBody{
+		ID:              round.ID,
+		ShoeID:          round.Shoe.ID,
+		Status:          round.Status,
+		Rules:           round.Rules,
+		PlayerHands:     round.PlayerHands,
+		ActiveHand:      round.ActiveHand,
+		DealerCards:     dealerCards,
+		DealerValue:     CalculateBlackjackValueForCards(dealerCards),
+		InsuranceBet:    round.InsuranceBet,
+		InsurancePayout: round.InsurancePayout,
+		EvenMoney:       round.EvenMoney,
+		SideBets:        round.SideBetResults,
+		NetResult:       round.NetResult,
+		Reshuffled:      round.Reshuffled,
+	}
+}
+
+// Handlers
+
+func CreateBlackjackRoundHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var roundBody BlackjackRoundBody
+	err := json.NewDecoder(r.Body).Decode(&roundBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	if roundBody.Bet <= 0 {
+		http.Error(w, "Bet must be positive", http.StatusBadRequest)
+		return
+	}
+
+	rules := DefaultBlackjackRules()
+	if roundBody.Rules != nil {
+		rules = *roundBody.Rules
+	}
+	rulesErr := ValidateBlackjackRules(rules)
+	if rulesErr != nil {
+		http.Error(w, rulesErr.Error(), http.StatusBadRequest)
+		return
+	}
+	sideBetsErr := ValidateBlackjackSideBets(roundBody.SideBets)
+	if sideBetsErr != nil {
+		http.Error(w, sideBetsErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	shoe, found := FindShoe(roundBody.ShoeID)
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	round := NewBlackjackRoundWithSideBets(shoe, roundBody.Bet, rules, roundBody.SideBets)
+	round.ID = NewRandomID()
+	BlackjackRounds[round.ID] = round
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(MakeBlackjackRoundResponseBody(round))
+}
+
+func GetBlackjackRoundHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	roundID := vars["id"]
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	round, found := BlackjackRounds[roundID]
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	json.NewEncoder(w).Encode(MakeBlackjackRoundResponseBody(round))
+}
+
+func BlackjackRoundActionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var actionBody BlackjackActionBody
+	err := json.NewDecoder(r.Body).Decode(&actionBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	roundID := vars["id"]
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	round, found := BlackjackRounds[roundID]
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	actionErr := round.Act(actionBody.Action)
+	if actionErr != nil {
+		http.Error(w, actionErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(MakeBlackjackRoundResponseBody(round))
+}
+
+func GetInsuranceStrategyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var insuranceBody InsuranceBody
+	err := json.NewDecoder(r.Body).Decode(&insuranceBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	if CalculateBlackjackValueForCard(insuranceBody.UpCard) != 11 {
+		http.Error(w, "Insurance is only offered against an ace", http.StatusBadRequest)
+		return
+	}
+
+	var composition *Composition
+	if insuranceBody.ShoeID != "" {
+		mutex.Lock()
+		shoe, found := FindShoe(insuranceBody.ShoeID)
+		if found {
+			remaining := CompositionFromCards(shoe.RemainingCards())
+			composition = &remaining
+		}
+		mutex.Unlock()
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(MakeInsuranceResponseBody(insuranceBody.Cards, insuranceBody.TrueCount, composition))
+}
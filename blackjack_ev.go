@@ -0,0 +1,397 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Structs
+
+type Composition [11]int
+
+type dealerState struct {
+	Composition Composition
+	Total       int
+	HasAce      bool
+	Cards       int
+}
+
+type playerState struct {
+	Composition Composition
+	Total       int
+	HasAce      bool
+}
+
+type BlackjackEVCalculator struct {
+	Rules       BlackjackRules
+	UpCardValue int
+	dealerMemo  map[dealerState][7]float64
+	standMemo   map[playerState]float64
+	hitMemo     map[playerState]float64
+}
+
+type BlackjackEVBody struct {
+	Cards       []string        `json:"cards"`
+	UpCard      string          `json:"upCard"`
+	Rules       *BlackjackRules `json:"rules"`
+	ShoeID      string          `json:"shoeId"`
+	Composition map[string]int  `json:"composition"`
+}
+
+type BlackjackEVResponseBody struct {
+	Actions    map[string]float64 `json:"actions"`
+	BestAction string             `json:"bestAction"`
+	CardsLeft  int                `json:"cardsLeft"`
+}
+
+// Package Variables
+
+// Dealer outcome indices: 17 through 21, bust and blackjack
+const dealerBustIndex = 5
+const dealerBlackjackIndex = 6
+
+// Functions
+
+func CompositionIndexForCard(card string) int {
+	value := CalculateBlackjackValueForCard(card)
+	if value == 11 {
+		return 1
+	}
+	return value
+}
+
+func NewComposition(decks int) Composition {
+	var composition Composition
+	for value := 1; value <= 9; value++ {
+		composition[value] = 4 * decks
+	}
+	composition[10] = 16 * decks
+	return composition
+}
+
+func CompositionFromCards(cards []Card) Composition {
+	var composition Composition
+	for _, card := range cards {
+		composition[CompositionIndexForCard(card.String())]++
+	}
+	return composition
+}
+
+func CompositionFromCounts(counts map[string]int) (Composition, error) {
+	var composition Composition
+	for rank, count := range counts {
+		index := CompositionIndexForCard(rank)
+		if index < 1 || count < 0 {
+			return composition, fmt.Errorf("invalid composition entry %q: %d", rank, count)
+		}
+		composition[index] += count
+	}
+	return composition, nil
+}
+
+func (c Composition) Total() int {
+	total := 0
+	for value := 1; value <= 10; value++ {
+		total += c[value]
+	}
+	return total
+}
+
+func (c *Composition) Remove(card string) bool {
+	index := CompositionIndexForCard(card)
+	if index < 1 || c[index] == 0 {
+		return false
+	}
+	c[index]--
+	return true
+}
+
+func EffectiveBlackjackTotal(total int, hasAce bool) int {
+	if hasAce && total+10 <= 21 {
+		return total + 10
+	}
+	return total
+}
+
+func NewBlackjackEVCalculator(rules BlackjackRules, upCard string) *BlackjackEVCalculator {
+	return &BlackjackEVCalculator{
+		Rules:       rules,
+		UpCardValue: CompositionIndexForCard(upCard),
+		dealerMemo:  make(map[dealerState][7]float64),
+		standMemo:   make(map[playerState]float64),
+		hitMemo:     make(map[playerState]float64),
+	}
+}
+
+func (calculator *BlackjackEVCalculator) DealerProbabilities(composition Composition) [7]float64 {
+	upCardValue := calculator.UpCardValue
+	return calculator.dealerProbabilities(dealerState{Composition: composition, Total: upCardValue, HasAce: upCardValue == 1, Cards: 1})
+}
+
+func (calculator *BlackjackEVCalculator) dealerProbabilities(state dealerState) [7]float64 {
+	var outcomes [7]float64
+
+	total := EffectiveBlackjackTotal(state.Total, state.HasAce)
+	if state.Cards >= 2 {
+		isSoft17 := total == 17 && state.HasAce && state.Total == 7
+		switch {
+		case state.Cards == 2 && total == 21:
+			outcomes[dealerBlackjackIndex] = 1
+			return outcomes
+		case total > 21:
+			outcomes[dealerBustIndex] = 1
+			return outcomes
+		case total >= 17 && !(isSoft17 && calculator.Rules.DealerHitsSoft17):
+			outcomes[total-17] = 1
+			return outcomes
+		}
+	}
+
+	if cached, found := calculator.dealerMemo[state]; found {
+		return cached
+	}
+
+	// When the dealer has already peeked the hole card cannot complete a blackjack
+	excludedValue := 0
+	if state.Cards == 1 && calculator.Rules.DealerPeeks {
+		if state.Total == 1 {
+			excludedValue = 10
+		} else if state.Total == 10 {
+			excludedValue = 1
+		}
+	}
+
+	cardsLeft := state.Composition.Total() - state.Composition[excludedValue]
+	for value := 1; value <= 10; value++ {
+		count := state.Composition[value]
+		if count == 0 || value == excludedValue {
+			continue
+		}
+		probability := float64(count) / float64(cardsLeft)
+
+		nextState := state
+		nextState.Composition[value]--
+		nextState.Total += value
+		nextState.HasAce = state.HasAce || value == 1
+		nextState.Cards++
+
+		nextOutcomes := calculator.dealerProbabilities(nextState)
+		for i := range outcomes {
+			outcomes[i] += probability * nextOutcomes[i]
+		}
+	}
+
+	calculator.dealerMemo[state] = outcomes
+	return outcomes
+}
+
+func (calculator *BlackjackEVCalculator) StandEV(state playerState) float64 {
+	playerTotal := EffectiveBlackjackTotal(state.Total, state.HasAce)
+	if playerTotal > 21 {
+		return -1
+	}
+
+	if cached, found := calculator.standMemo[state]; found {
+		return cached
+	}
+
+	outcomes := calculator.DealerProbabilities(state.Composition)
+	ev := outcomes[dealerBustIndex] - outcomes[dealerBlackjackIndex]
+	for dealerTotal := 17; dealerTotal <= 21; dealerTotal++ {
+		if playerTotal > dealerTotal {
+			ev += outcomes[dealerTotal-17]
+		} else if playerTotal < dealerTotal {
+			ev -= outcomes[dealerTotal-17]
+		}
+	}
+
+	calculator.standMemo[state] = ev
+	return ev
+}
+
+func (calculator *BlackjackEVCalculator) drawOutcomes(state playerState, evaluate func(playerState) float64) float64 {
+	cardsLeft := state.Composition.Total()
+	ev := 0.0
+	for value := 1; value <= 10; value++ {
+		count := state.Composition[value]
+		if count == 0 {
+			continue
+		}
+		nextState := state
+		nextState.Composition[value]--
+		nextState.Total += value
+		nextState.HasAce = state.HasAce || value == 1
+		ev += float64(count) / float64(cardsLeft) * evaluate(nextState)
+	}
+	return ev
+}
+
+func (calculator *BlackjackEVCalculator) BestEV(state playerState) float64 {
+	standEV := calculator.StandEV(state)
+	if EffectiveBlackjackTotal(state.Total, state.HasAce) >= 21 {
+		return standEV
+	}
+	hitEV := calculator.HitEV(state)
+	if hitEV > standEV {
+		return hitEV
+	}
+	return standEV
+}
+
+func (calculator *BlackjackEVCalculator) HitEV(state playerState) float64 {
+	if cached, found := calculator.hitMemo[state]; found {
+		return cached
+	}
+
+	ev := calculator.drawOutcomes(state, func(nextState playerState) float64 {
+		if EffectiveBlackjackTotal(nextState.Total, nextState.HasAce) > 21 {
+			return -1
+		}
+		return calculator.BestEV(nextState)
+	})
+
+	calculator.hitMemo[state] = ev
+	return ev
+}
+
+func (calculator *BlackjackEVCalculator) DoubleEV(state playerState) float64 {
+	return 2 * calculator.drawOutcomes(state, calculator.StandEV)
+}
+
+func (calculator *BlackjackEVCalculator) SplitEV(state playerState, pairValue int) float64 {
+	// Each split hand is played against the same composition and is not resplit
+	splitState := playerState{Composition: state.Composition, Total: pairValue, HasAce: pairValue == 1}
+	handEV := calculator.drawOutcomes(splitState, func(nextState playerState) float64 {
+		if pairValue == 1 {
+			return calculator.StandEV(nextState)
+		}
+		best := calculator.BestEV(nextState)
+		if calculator.Rules.DoubleAfterSplit {
+			doubleEV := calculator.DoubleEV(nextState)
+			if doubleEV > best {
+				best = doubleEV
+			}
+		}
+		return best
+	})
+	return 2 * handEV
+}
+
+func CalculateBlackjackEVs(cards []string, upCard string, composition Composition, rules BlackjackRules) map[string]float64 {
+	calculator := NewBlackjackEVCalculator(rules, upCard)
+
+	state := playerState{Composition: composition}
+	for _, card := range cards {
+		value := CompositionIndexForCard(card)
+		state.Total += value
+		state.HasAce = state.HasAce || value == 1
+	}
+
+	evs := make(map[string]float64)
+	if CalculateIsBlackjack(cards) {
+		outcomes := calculator.DealerProbabilities(composition)
+		evs["STAND"] = BlackjackPayouts[rules.BlackjackPayout] * (1 - outcomes[dealerBlackjackIndex])
+		return evs
+	}
+
+	evs["STAND"] = calculator.StandEV(state)
+	if EffectiveBlackjackTotal(state.Total, state.HasAce) < 21 {
+		evs["HIT"] = calculator.HitEV(state)
+	}
+	if len(cards) == 2 {
+		evs["DOUBLE"] = calculator.DoubleEV(state)
+		if rules.Surrender != SurrenderNone {
+			evs["SURRENDER"] = -0.5
+		}
+		if cards[0][0] == cards[1][0] && rules.MaxSplits > 0 {
+			evs["SPLIT"] = calculator.SplitEV(state, CompositionIndexForCard(cards[0]))
+		}
+	}
+
+	return evs
+}
+
+func FindBestAction(evs map[string]float64) string {
+	bestAction := ""
+	for _, action := range []string{"STAND", "HIT", "DOUBLE", "SPLIT", "SURRENDER"} {
+		ev, found := evs[action]
+		if found && (bestAction == "" || ev > evs[bestAction]) {
+			bestAction = action
+		}
+	}
+	return bestAction
+}
+
+// Handlers
+
+func GetBlackjackEVHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var evBody BlackjackEVBody
+	err := json.NewDecoder(r.Body).Decode(&evBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	rules := DefaultBlackjackRules()
+	if evBody.Rules != nil {
+		rules = *evBody.Rules
+	}
+	rulesErr := ValidateBlackjackRules(rules)
+	if rulesErr != nil {
+		http.Error(w, rulesErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(evBody.Cards) < 2 || CompositionIndexForCard(evBody.UpCard) < 1 {
+		http.Error(w, "At least two cards and an up card are required", http.StatusBadRequest)
+		return
+	}
+	for _, card := range evBody.Cards {
+		if CompositionIndexForCard(card) < 1 {
+			http.Error(w, fmt.Sprintf("Invalid card %q", card), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Cards already drawn from a live shoe are gone, otherwise the hand and up card come out of the composition
+	var composition Composition
+	switch {
+	case evBody.ShoeID != "":
+		mutex.Lock()
+		shoe, found := FindShoe(evBody.ShoeID)
+		if found {
+			composition = CompositionFromCards(shoe.RemainingCards())
+		}
+		mutex.Unlock()
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+	default:
+		composition = NewComposition(rules.Decks)
+		if evBody.Composition != nil {
+			var compositionErr error
+			composition, compositionErr = CompositionFromCounts(evBody.Composition)
+			if compositionErr != nil {
+				http.Error(w, compositionErr.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		for _, card := range append([]string{evBody.UpCard}, evBody.Cards...) {
+			if !composition.Remove(card) {
+				http.Error(w, fmt.Sprintf("Card %q is not in the composition", card), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	if composition.Total() < 10 {
+		http.Error(w, "Too few cards left in the composition", http.StatusBadRequest)
+		return
+	}
+
+	evs := CalculateBlackjackEVs(evBody.Cards, evBody.UpCard, composition, rules)
+	json.NewEncoder(w).Encode(BlackjackEVResponseBody{Actions: evs, BestAction: FindBestAction(evs), CardsLeft: composition.Total()})
+}
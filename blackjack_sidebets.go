@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// Structs
+
+type SideBetResult struct {
+	Name       string  `json:"name"`
+	Bet        float64 `json:"bet"`
+	Outcome    string  `json:"outcome,omitempty"`
+	Multiplier float64 `json:"multiplier"`
+	Payout     float64 `json:"payout"`
+}
+
+type BlackjackSideBetsBody struct {
+	PlayerCards []string           `json:"playerCards"`
+	DealerCards []string           `json:"dealerCards"`
+	SideBets    map[string]float64 `json:"sideBets"`
+}
+
+// Package Variables
+
+const SideBetPerfectPairs = "PERFECT_PAIRS"
+const SideBetTwentyOnePlusThree = "21_PLUS_3"
+const SideBetLuckyLadies = "LUCKY_LADIES"
+const SideBetBuster = "BUSTER"
+
+var PerfectPairsPaytable = map[string]float64{
+	"Perfect Pair": 25,
+	"Colored Pair": 12,
+	"Mixed Pair":   6,
+}
+
+var TwentyOnePlusThreePaytable = map[string]float64{
+	"Suited Three Of A Kind": 100,
+	"Straight Flush":         40,
+	"Three Of A Kind":        30,
+	"Straight":               10,
+	"Flush":                  5,
+}
+
+var LuckyLadiesPaytable = map[string]float64{
+	"Queen Of Hearts Pair With Dealer Blackjack": 1000,
+	"Queen Of Hearts Pair":                       200,
+	"Matched 20":                                 25,
+	"Suited 20":                                  10,
+	"Any 20":                                     4,
+}
+
+// Indexed by the number of cards in the dealer's busted hand, with eight or more paying the top award
+var BusterPaytable = map[int]float64{3: 2, 4: 2, 5: 4, 6: 12, 7: 50, 8: 250}
+
+var BlackjackSideBetPaytables = map[string]map[string]float64{
+	SideBetPerfectPairs:       PerfectPairsPaytable,
+	SideBetTwentyOnePlusThree: TwentyOnePlusThreePaytable,
+	SideBetLuckyLadies:        LuckyLadiesPaytable,
+}
+
+// Functions
+
+func IsRedSuit(suit byte) bool {
+	return suit == 'H' || suit == 'D'
+}
+
+func EvaluatePerfectPairs(playerCards []string) string {
+	first, second := playerCards[0], playerCards[1]
+	switch {
+	case first[0] != second[0]:
+		return ""
+	case first[1] == second[1]:
+		return "Perfect Pair"
+	case IsRedSuit(first[1]) == IsRedSuit(second[1]):
+		return "Colored Pair"
+	default:
+		return "Mixed Pair"
+	}
+}
+
+func EvaluateTwentyOnePlusThree(playerCards []string, dealerUpCard string) string {
+	// A multi-deck shoe can deal the same card more than once, so the cards aren't run through ParsePokerCards
+	sortedCards := SortPokerCards([]PokerCard{NewPokerCard(playerCards[0]), NewPokerCard(playerCards[1]), NewPokerCard(dealerUpCard)})
+
+	isFlush := FindFlush(sortedCards, 3) != nil
+	isTrips := sortedCards[0].Rank == sortedCards[2].Rank
+	// Aces play high or low, so Q-K-A and A-2-3 both count
+	isStraight := FindStraight(sortedCards, 3) != nil
+
+	switch {
+	case isTrips && isFlush:
+		return "Suited " + FindPokerHandType("Three Of A Kind").Name
+	case isStraight && isFlush:
+		return FindPokerHandType("Straight Flush").Name
+	case isTrips:
+		return FindPokerHandType("Three Of A Kind").Name
+	case isStraight:
+		return FindPokerHandType("Straight").Name
+	case isFlush:
+		return FindPokerHandType("Flush").Name
+	}
+	return ""
+}
+
+func EvaluateLuckyLadies(playerCards []string, dealerCards []string) string {
+	first, second := playerCards[0], playerCards[1]
+	if CalculateBlackjackValueForCards(playerCards[:2]) != 20 {
+		return ""
+	}
+
+	switch {
+	case first == "QH" && second == "QH" && CalculateIsBlackjack(dealerCards):
+		return "Queen Of Hearts Pair With Dealer Blackjack"
+	case first == "QH" && second == "QH":
+		return "Queen Of Hearts Pair"
+	case first == second:
+		return "Matched 20"
+	case first[1] == second[1]:
+		return "Suited 20"
+	default:
+		return "Any 20"
+	}
+}
+
+func EvaluateBuster(dealerCards []string) (string, float64) {
+	if CalculateBlackjackValueForCards(dealerCards) <= 21 {
+		return "", 0
+	}
+
+	cardCount := len(dealerCards)
+	if cardCount > 8 {
+		cardCount = 8
+	}
+	return fmt.Sprintf("Dealer Busts With %d Cards", len(dealerCards)), BusterPaytable[cardCount]
+}
+
+func EvaluateBlackjackSideBet(name string, bet float64, playerCards []string, dealerCards []string) SideBetResult {
+	var outcome string
+	var multiplier float64
+
+	switch name {
+	case SideBetPerfectPairs:
+		outcome = EvaluatePerfectPairs(playerCards)
+	case SideBetTwentyOnePlusThree:
+		outcome = EvaluateTwentyOnePlusThree(playerCards, dealerCards[0])
+	case SideBetLuckyLadies:
+		outcome = EvaluateLuckyLadies(playerCards, dealerCards)
+	case SideBetBuster:
+		outcome, multiplier = EvaluateBuster(dealerCards)
+	}
+	if paytable, found := BlackjackSideBetPaytables[name]; found {
+		multiplier = paytable[outcome]
+	}
+
+	result := SideBetResult{Name: name, Bet: bet, Outcome: outcome, Multiplier: multiplier, Payout: -bet}
+	if outcome != "" {
+		result.Payout = bet * multiplier
+	}
+	return result
+}
+
+func ValidateBlackjackSideBets(sideBets map[string]float64) error {
+	for name, bet := range sideBets {
+		switch name {
+		case SideBetPerfectPairs, SideBetTwentyOnePlusThree, SideBetLuckyLadies, SideBetBuster:
+		default:
+			return fmt.Errorf("unknown side bet %q", name)
+		}
+		if bet <= 0 {
+			return fmt.Errorf("side bet %s must be positive", name)
+		}
+	}
+	return nil
+}
+
+func EvaluateBlackjackSideBets(sideBets map[string]float64, playerCards []string, dealerCards []string) []SideBetResult {
+	var names []string
+	for name := range sideBets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := []SideBetResult{}
+	for _, name := range names {
+		results = append(results, EvaluateBlackjackSideBet(name, sideBets[name], playerCards, dealerCards))
+	}
+	return results
+}
+
+// Handlers
+
+func GetBlackjackSideBetsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var sideBetsBody BlackjackSideBetsBody
+	err := json.NewDecoder(r.Body).Decode(&sideBetsBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	if len(sideBetsBody.PlayerCards) != 2 || len(sideBetsBody.DealerCards) < 1 {
+		http.Error(w, "Two player cards and at least one dealer card are required", http.StatusBadRequest)
+		return
+	}
+	for _, card := range append(append([]string{}, sideBetsBody.PlayerCards...), sideBetsBody.DealerCards...) {
+		if !IsValidCard(card) {
+			http.Error(w, fmt.Sprintf("Invalid card %q", card), http.StatusBadRequest)
+			return
+		}
+	}
+
+	validationErr := ValidateBlackjackSideBets(sideBetsBody.SideBets)
+	if validationErr != nil {
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(EvaluateBlackjackSideBets(sideBetsBody.SideBets, sideBetsBody.PlayerCards, sideBetsBody.DealerCards))
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func newSequencedShoe(cards []string) *Shoe {
+	shoe := NewShoeWithSource(1, NewSeededSource(1))
+	shoe.SequencedCards = MakeCardsFromStrings(cards)
+	return shoe
+}
+
+func TestBlackjackRoundDealing(t *testing.T) {
+	round := NewBlackjackRound(newSequencedShoe([]string{"TS", "9H", "6D", "7C", "5S"}), 10, DefaultBlackjackRules())
+	if round.Status != RoundStatusPlayerTurn {
+		t.Errorf("player turn expected")
+	}
+	if len(MakeBlackjackRoundResponseBody(round).DealerCards) != 1 {
+		t.Errorf("hole card expected to be hidden")
+	}
+	round.Act("STAND")
+	if round.Status != RoundStatusSettled || round.PlayerHands[0].Result != "LOSE" || round.NetResult != -10 {
+		t.Errorf("dealer 21 expected to beat player 16")
+	}
+
+	round = NewBlackjackRound(newSequencedShoe([]string{"TS", "AH", "8D", "KC"}), 10, DefaultBlackjackRules())
+	if round.Status != RoundStatusInsurance || len(MakeBlackjackRoundResponseBody(round).DealerCards) != 1 {
+		t.Errorf("ace up card expected to offer insurance with the hole card hidden")
+	}
+	round.Act("DECLINE_INSURANCE")
+	if round.Status != RoundStatusSettled || round.NetResult != -10 {
+		t.Errorf("dealer blackjack expected to settle immediately")
+	}
+
+	round = NewBlackjackRound(newSequencedShoe([]string{"AS", "9H", "KD", "7C"}), 10, DefaultBlackjackRules())
+	if round.PlayerHands[0].Result != "BLACKJACK" || round.NetResult != 15 {
+		t.Errorf("player blackjack expected to pay 3:2")
+	}
+
+	round = NewBlackjackRound(newSequencedShoe([]string{"AS", "AH", "KD", "KC"}), 10, DefaultBlackjackRules())
+	round.Act("DECLINE_INSURANCE")
+	if round.PlayerHands[0].Result != "PUSH" || round.NetResult != 0 {
+		t.Errorf("blackjack against blackjack expected to push")
+	}
+}
+
+func TestBlackjackRoundActions(t *testing.T) {
+	round := NewBlackjackRound(newSequencedShoe([]string{"5S", "6H", "6D", "TC", "TS", "9D"}), 10, DefaultBlackjackRules())
+	if err := round.Act("DOUBLE"); err != nil {
+		t.Errorf("unexpected double error: %v", err)
+	}
+	if round.PlayerHands[0].Result != "WIN" || round.NetResult != 20 {
+		t.Errorf("doubled win expected to pay twice the bet")
+	}
+
+	round = NewBlackjackRound(newSequencedShoe([]string{"8S", "7H", "8D", "TC", "3S", "TD", "TH"}), 10, DefaultBlackjackRules())
+	if err := round.Act("SPLIT"); err != nil {
+		t.Errorf("unexpected split error: %v", err)
+	}
+	if len(round.PlayerHands) != 2 {
+		t.Errorf("2 hands expected after split")
+	}
+	if round.Act("SPLIT") == nil {
+		t.Errorf("second split not expected")
+	}
+	round.Act("HIT")
+	round.Act("STAND")
+	if round.PlayerHands[0].Result != "WIN" || round.PlayerHands[1].Result != "WIN" || round.NetResult != 20 {
+		t.Errorf("18 and 21 expected to beat dealer 17")
+	}
+
+	round = NewBlackjackRound(newSequencedShoe([]string{"TS", "9H", "6D", "TC"}), 10, DefaultBlackjackRules())
+	round.Act("SURRENDER")
+	if round.PlayerHands[0].Result != "SURRENDER" || round.NetResult != -5 {
+		t.Errorf("surrender expected to lose half the bet")
+	}
+	if round.Act("HIT") == nil {
+		t.Errorf("actions not expected after settlement")
+	}
+
+	round = NewBlackjackRound(newSequencedShoe([]string{"TS", "9H", "6D", "TC", "9S"}), 10, DefaultBlackjackRules())
+	round.Act("HIT")
+	if round.PlayerHands[0].Result != "BUST" || len(round.DealerCards) != 2 {
+		t.Errorf("dealer not expected to draw against a busted hand")
+	}
+}
+
+func TestBlackjackRules(t *testing.T) {
+	var rules BlackjackRules
+	if err := json.Unmarshal([]byte(`{"dealerHitsSoft17": true}`), &rules); err != nil {
+		t.Fatalf("unexpected rules error: %v", err)
+	}
+	if !rules.DealerHitsSoft17 || !rules.DoubleAfterSplit || rules.BlackjackPayout != "3:2" {
+		t.Errorf("omitted rules expected to keep their defaults")
+	}
+
+	rules.BlackjackPayout = "2:1"
+	if ValidateBlackjackRules(rules) == nil {
+		t.Errorf("unknown payout expected to be rejected")
+	}
+
+	h17 := DefaultBlackjackRules()
+	h17.DealerHitsSoft17 = true
+	s17 := DefaultBlackjackRules()
+
+	if CalculateStrategyDecisionForRules([]string{"6", "5"}, "AS", h17) != "DOUBLE" {
+		t.Errorf("11 vs A expected to double when the dealer hits soft 17")
+	}
+	if CalculateStrategyDecisionForRules([]string{"6", "5"}, "AS", s17) != "HIT" {
+		t.Errorf("11 vs A expected to hit when the dealer stands on soft 17")
+	}
+	if CalculateStrategyDecisionForRules([]string{"A", "7"}, "2S", h17) != "DOUBLE" {
+		t.Errorf("soft 18 vs 2 expected to double when the dealer hits soft 17")
+	}
+
+	if CalculateStrategyDecisionForRules([]string{"T", "6"}, "TS", s17) != "SURRENDER" {
+		t.Errorf("16 vs 10 expected to surrender")
+	}
+	if CalculateStrategyDecisionForRules([]string{"8", "8"}, "AS", s17) != "SPLIT" {
+		t.Errorf("8-8 vs A expected to split when the dealer stands on soft 17")
+	}
+	if CalculateStrategyDecisionForRules([]string{"8", "8"}, "AS", h17) != "SURRENDER" {
+		t.Errorf("8-8 vs A expected to surrender when the dealer hits soft 17")
+	}
+	if CalculateStrategyDecision([]string{"T", "6"}, "TS") != "HIT" {
+		t.Errorf("legacy chart not expected to surrender")
+	}
+
+	early := DefaultBlackjackRules()
+	early.Surrender = SurrenderEarly
+	if CalculateStrategyDecisionForRules([]string{"T", "3"}, "AS", early) != "SURRENDER" {
+		t.Errorf("13 vs A expected to surrender early")
+	}
+
+	noDAS := DefaultBlackjackRules()
+	noDAS.DoubleAfterSplit = false
+	if CalculateStrategyDecisionForRules([]string{"4", "4"}, "5S", noDAS) != "HIT" {
+		t.Errorf("4-4 expected to hit without double after split")
+	}
+	if CalculateStrategyDecisionForRules([]string{"2", "2"}, "2S", noDAS) != "HIT" {
+		t.Errorf("2-2 vs 2 expected to hit without double after split")
+	}
+}
+
+func TestBlackjackRoundRules(t *testing.T) {
+	h17 := DefaultBlackjackRules()
+	h17.DealerHitsSoft17 = true
+	round := NewBlackjackRound(newSequencedShoe([]string{"TS", "AH", "8D", "6C", "3S"}), 10, h17)
+	round.Act("DECLINE_INSURANCE")
+	round.Act("STAND")
+	if len(round.DealerCards) != 3 || round.PlayerHands[0].Result != "LOSE" {
+		t.Errorf("dealer expected to hit soft 17")
+	}
+	round = NewBlackjackRound(newSequencedShoe([]string{"TS", "6H", "8D", "TC", "AS", "5S"}), 10, h17)
+	round.Act("STAND")
+	if len(round.DealerCards) != 3 || round.PlayerHands[0].Result != "WIN" {
+		t.Errorf("dealer expected to stand on hard 17 with an ace")
+	}
+
+	sixToFive := DefaultBlackjackRules()
+	sixToFive.BlackjackPayout = "6:5"
+	round = NewBlackjackRound(newSequencedShoe([]string{"AS", "9H", "KD", "7C"}), 10, sixToFive)
+	if round.NetResult != 12 {
+		t.Errorf("player blackjack expected to pay 6:5")
+	}
+
+	noSurrender := DefaultBlackjackRules()
+	noSurrender.Surrender = SurrenderNone
+	round = NewBlackjackRound(newSequencedShoe([]string{"TS", "9H", "6D", "TC"}), 10, noSurrender)
+	if round.Act("SURRENDER") == nil {
+		t.Errorf("surrender not expected to be allowed")
+	}
+
+	early := DefaultBlackjackRules()
+	early.Surrender = SurrenderEarly
+	round = NewBlackjackRound(newSequencedShoe([]string{"TS", "AH", "6D", "KC"}), 10, early)
+	round.Act("DECLINE_INSURANCE")
+	if round.Status != RoundStatusPlayerTurn {
+		t.Errorf("early surrender expected to be offered before the peek")
+	}
+	round.Act("SURRENDER")
+	if round.NetResult != -5 {
+		t.Errorf("early surrender expected to lose half the bet against a blackjack")
+	}
+}
+
+func TestBlackjackEVs(t *testing.T) {
+	rules := DefaultBlackjackRules()
+	compositionWithout := func(cards ...string) Composition {
+		composition := NewComposition(6)
+		for _, card := range cards {
+			composition.Remove(card)
+		}
+		return composition
+	}
+
+	evs := CalculateBlackjackEVs([]string{"T", "K"}, "6S", compositionWithout("T", "K", "6S"), rules)
+	if FindBestAction(evs) != "STAND" || evs["STAND"] < 0.6 {
+		t.Errorf("20 vs 6 expected to stand with a large edge")
+	}
+	if _, found := evs["SPLIT"]; found {
+		t.Errorf("split not expected for mixed tens")
+	}
+
+	evs = CalculateBlackjackEVs([]string{"6", "5"}, "6S", compositionWithout("6", "5", "6S"), rules)
+	if FindBestAction(evs) != "DOUBLE" || evs["DOUBLE"] <= 2*evs["STAND"] {
+		t.Errorf("11 vs 6 expected to double")
+	}
+
+	evs = CalculateBlackjackEVs([]string{"T", "6"}, "TS", compositionWithout("T", "6", "TS"), rules)
+	if FindBestAction(evs) != "SURRENDER" {
+		t.Errorf("16 vs 10 expected to surrender")
+	}
+
+	evs = CalculateBlackjackEVs([]string{"8", "8"}, "6S", compositionWithout("8", "8", "6S"), rules)
+	if FindBestAction(evs) != "SPLIT" {
+		t.Errorf("8-8 vs 6 expected to split")
+	}
+
+	evs = CalculateBlackjackEVs([]string{"A", "K"}, "TS", compositionWithout("A", "K", "TS"), rules)
+	if evs["STAND"] != 1.5 || len(evs) != 1 {
+		t.Errorf("blackjack against a peeked ten expected to pay 3:2")
+	}
+
+	outcomes := NewBlackjackEVCalculator(rules, "AS").DealerProbabilities(compositionWithout("AS"))
+	total := 0.0
+	for _, probability := range outcomes {
+		total += probability
+	}
+	if total < 0.999999 || total > 1.000001 || outcomes[dealerBlackjackIndex] != 0 {
+		t.Errorf("peeked dealer outcomes expected to sum to 1 without blackjack")
+	}
+}
+
+func TestBlackjackInsurance(t *testing.T) {
+	round := NewBlackjackRound(newSequencedShoe([]string{"TS", "AH", "8D", "KC"}), 10, DefaultBlackjackRules())
+	if round.Act("HIT") == nil {
+		t.Errorf("insurance decision expected before playing")
+	}
+	round.Act("INSURANCE")
+	if round.InsuranceBet != 5 || round.InsurancePayout != 10 || round.NetResult != 0 {
+		t.Errorf("insurance expected to cover the loss to a dealer blackjack")
+	}
+
+	round = NewBlackjackRound(newSequencedShoe([]string{"TS", "AH", "8D", "7C"}), 10, DefaultBlackjackRules())
+	round.Act("INSURANCE")
+	round.Act("STAND")
+	if round.PlayerHands[0].Result != "PUSH" || round.InsurancePayout != -5 || round.NetResult != -5 {
+		t.Errorf("insurance expected to lose when the dealer has no blackjack")
+	}
+
+	round = NewBlackjackRound(newSequencedShoe([]string{"AS", "AH", "KD", "KC"}), 10, DefaultBlackjackRules())
+	round.Act("EVEN_MONEY")
+	if round.Status != RoundStatusSettled || round.PlayerHands[0].Result != "EVEN_MONEY" || round.NetResult != 10 {
+		t.Errorf("even money expected to pay 1:1 against a dealer blackjack")
+	}
+
+	round = NewBlackjackRound(newSequencedShoe([]string{"TS", "AH", "8D", "7C"}), 10, DefaultBlackjackRules())
+	if round.Act("EVEN_MONEY") == nil {
+		t.Errorf("even money not expected without a blackjack")
+	}
+
+	trueCount := 3.0
+	if take, basis := CalculateInsuranceDecision(&trueCount, nil); !take || basis != InsuranceBasisTrueCount {
+		t.Errorf("insurance expected at a true count of 3")
+	}
+	trueCount = 2.9
+	if take, _ := CalculateInsuranceDecision(&trueCount, nil); take {
+		t.Errorf("insurance not expected below a true count of 3")
+	}
+	if take, basis := CalculateInsuranceDecision(nil, nil); take || basis != InsuranceBasisBasic {
+		t.Errorf("basic strategy expected to decline insurance")
+	}
+
+	composition := Composition{0, 2, 1, 1, 1, 1, 1, 1, 1, 0, 5}
+	if take, _ := CalculateInsuranceDecision(nil, &composition); !take {
+		t.Errorf("insurance expected with more than a third tens")
+	}
+	composition[10] = 4
+	if take, _ := CalculateInsuranceDecision(nil, &composition); take {
+		t.Errorf("insurance not expected with exactly a third tens")
+	}
+
+	trueCount = 4
+	if MakeInsuranceResponseBody([]string{"A", "K"}, &trueCount, nil).Decision != "EVEN_MONEY" {
+		t.Errorf("even money expected for a blackjack at a high count")
+	}
+}
+
+func TestNoHoleCard(t *testing.T) {
+	enhc := DefaultBlackjackRules()
+	enhc.DealerPeeks = false
+
+	round := NewBlackjackRound(newSequencedShoe([]string{"6S", "TH", "5D", "AC", "9S"}), 10, enhc)
+	if round.Status != RoundStatusPlayerTurn {
+		t.Errorf("dealer not expected to peek without a hole card check")
+	}
+	round.Act("DOUBLE")
+	if round.PlayerHands[0].Result != "LOSE" || round.NetResult != -20 {
+		t.Errorf("doubled bet expected to be lost to a dealer blackjack")
+	}
+
+	if CalculateStrategyDecisionForRules([]string{"6", "5"}, "TS", enhc) != "HIT" {
+		t.Errorf("11 vs 10 expected to hit without a hole card check")
+	}
+	if CalculateStrategyDecisionForRules([]string{"8", "8"}, "TS", enhc) != "HIT" {
+		t.Errorf("8-8 vs 10 expected to hit without a hole card check")
+	}
+	if CalculateStrategyDecisionForRules([]string{"A", "A"}, "AS", enhc) != "HIT" {
+		t.Errorf("A-A vs A expected to hit without a hole card check")
+	}
+	if CalculateStrategyDecisionForRules([]string{"A", "A"}, "TS", enhc) != "SPLIT" {
+		t.Errorf("A-A vs 10 expected to split without a hole card check")
+	}
+
+	composition := NewComposition(6)
+	for _, card := range []string{"6", "5", "T"} {
+		composition.Remove(card)
+	}
+	peekEVs := CalculateBlackjackEVs([]string{"6", "5"}, "T", composition, DefaultBlackjackRules())
+	enhcEVs := CalculateBlackjackEVs([]string{"6", "5"}, "T", composition, enhc)
+	if enhcEVs["STAND"] >= peekEVs["STAND"] || FindBestAction(enhcEVs) != "HIT" {
+		t.Errorf("dealer blackjacks expected to cost more without a peek")
+	}
+}
+
+func TestBlackjackSplits(t *testing.T) {
+	round := NewBlackjackRound(newSequencedShoe([]string{"8S", "TC", "8D", "9C", "8H", "3S", "TD", "TH", "8C", "9S", "8S"}), 10, DefaultBlackjackRules())
+	round.Act("SPLIT")
+	if err := round.Act("SPLIT"); err != nil || len(round.PlayerHands) != 3 {
+		t.Errorf("resplit expected to create a third hand")
+	}
+	if len(round.PlayerHands[1].Cards) != 1 || len(round.PlayerHands[2].Cards) != 1 {
+		t.Errorf("waiting split hands expected to hold one card")
+	}
+	round.Act("DOUBLE")
+	round.Act("STAND")
+	round.Act("SPLIT")
+	round.Act("STAND")
+	if round.Act("SPLIT") == nil {
+		t.Errorf("split beyond the limit not expected to be allowed")
+	}
+	round.Act("STAND")
+	if round.Status != RoundStatusSettled || len(round.PlayerHands) != 4 || round.NetResult != -10 {
+		t.Errorf("four split hands expected to settle for a net loss of 10")
+	}
+
+	round = NewBlackjackRound(newSequencedShoe([]string{"AS", "TC", "AD", "7C", "KS", "AH"}), 10, DefaultBlackjackRules())
+	round.Act("SPLIT")
+	if round.Status != RoundStatusSettled {
+		t.Errorf("split aces expected to receive one card each")
+	}
+	if round.PlayerHands[0].Result != "WIN" || round.PlayerHands[0].Payout != 10 {
+		t.Errorf("21 on split aces not expected to pay as a blackjack")
+	}
+	if round.PlayerHands[1].Result != "LOSE" {
+		t.Errorf("aces not expected to be resplit")
+	}
+
+	resplitAces := DefaultBlackjackRules()
+	resplitAces.ResplitAces = true
+	round = NewBlackjackRound(newSequencedShoe([]string{"AS", "TC", "AD", "7C", "AH", "9S", "5S", "6S"}), 10, resplitAces)
+	round.Act("SPLIT")
+	if round.Act("HIT") == nil {
+		t.Errorf("split aces not expected to take a hit")
+	}
+	round.Act("SPLIT")
+	if round.Status != RoundStatusSettled || len(round.PlayerHands) != 3 || round.NetResult != 0 {
+		t.Errorf("resplit aces expected to settle three hands")
+	}
+
+	noSplits := DefaultBlackjackRules()
+	noSplits.MaxSplits = 0
+	round = NewBlackjackRound(newSequencedShoe([]string{"8S", "TC", "8D", "9C"}), 10, noSplits)
+	if round.Act("SPLIT") == nil {
+		t.Errorf("split not expected to be allowed without splits")
+	}
+}
+
+func TestBlackjackSideBets(t *testing.T) {
+	perfectPairs := map[string][]string{
+		"Perfect Pair": {"8S", "8S"},
+		"Colored Pair": {"8H", "8D"},
+		"Mixed Pair":   {"8C", "8D"},
+		"":             {"8C", "9C"},
+	}
+	for outcome, cards := range perfectPairs {
+		if EvaluatePerfectPairs(cards) != outcome {
+			t.Errorf("perfect pairs expected %q for %v", outcome, cards)
+		}
+	}
+
+	twentyOnePlusThree := map[string][]string{
+		"Suited Three Of A Kind": {"7H", "7H", "7H"},
+		"Straight Flush":         {"9S", "TS", "JS"},
+		"Three Of A Kind":        {"7H", "7S", "7D"},
+		"Straight":               {"AS", "2D", "3C"},
+		"Flush":                  {"2C", "9C", "KC"},
+		"":                       {"KS", "AD", "2C"},
+	}
+	for outcome, cards := range twentyOnePlusThree {
+		if EvaluateTwentyOnePlusThree(cards[:2], cards[2]) != outcome {
+			t.Errorf("21+3 expected %q for %v", outcome, cards)
+		}
+	}
+	if EvaluateTwentyOnePlusThree([]string{"QD", "KH"}, "AS") != "Straight" {
+		t.Errorf("ace high straight expected")
+	}
+
+	luckyLadies := map[string][][]string{
+		"Queen Of Hearts Pair With Dealer Blackjack": {{"QH", "QH"}, {"AS", "KD"}},
+		"Queen Of Hearts Pair":                       {{"QH", "QH"}, {"9S", "KD"}},
+		"Matched 20":                                 {{"KS", "KS"}, {"9S", "KD"}},
+		"Suited 20":                                  {{"KS", "TS"}, {"9S", "KD"}},
+		"Any 20":                                     {{"AS", "9D"}, {"9S", "KD"}},
+		"":                                           {{"KS", "9S"}, {"9S", "KD"}},
+	}
+	for outcome, hands := range luckyLadies {
+		if EvaluateLuckyLadies(hands[0], hands[1]) != outcome {
+			t.Errorf("lucky ladies expected %q for %v", outcome, hands[0])
+		}
+	}
+
+	if _, multiplier := EvaluateBuster([]string{"2S", "3S", "4S", "2D", "3D", "4D", "2H", "TH", "TC"}); multiplier != 250 {
+		t.Errorf("nine card bust expected to pay the top award")
+	}
+	if _, multiplier := EvaluateBuster([]string{"TS", "6D", "KH"}); multiplier != 2 {
+		t.Errorf("three card bust expected to pay 2:1")
+	}
+	if outcome, _ := EvaluateBuster([]string{"TS", "7D"}); outcome != "" {
+		t.Errorf("standing dealer expected to lose buster")
+	}
+
+	results := EvaluateBlackjackSideBets(map[string]float64{SideBetPerfectPairs: 5, SideBetTwentyOnePlusThree: 5}, []string{"8H", "8D"}, []string{"8S"})
+	if len(results) != 2 || results[0].Name != SideBetTwentyOnePlusThree || results[0].Payout != 150 || results[1].Payout != 60 {
+		t.Errorf("side bets expected to be paid from their paytables")
+	}
+
+	if ValidateBlackjackSideBets(map[string]float64{"DRAGON": 5}) == nil {
+		t.Errorf("unknown side bet expected to be rejected")
+	}
+	if ValidateBlackjackSideBets(map[string]float64{SideBetBuster: 0}) == nil {
+		t.Errorf("zero side bet expected to be rejected")
+	}
+
+	// Every player hand busts, but the dealer still plays out for Buster
+	round := NewBlackjackRoundWithSideBets(newSequencedShoe([]string{"TS", "6H", "6D", "TC", "KS", "6C"}), 10, DefaultBlackjackRules(), map[string]float64{SideBetBuster: 5, SideBetPerfectPairs: 5})
+	round.Act("HIT")
+	if round.Status != RoundStatusSettled || len(round.DealerCards) != 3 {
+		t.Errorf("dealer expected to play out for buster")
+	}
+	if len(round.SideBetResults) != 2 || round.NetResult != -10+10-5 {
+		t.Errorf("round net result expected to include side bets")
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Structs
+
+type CountingSystem struct {
+	Name     string
+	Tags     map[byte]int
+	Balanced bool
+}
+
+type CountResponseBody struct {
+	System         string   `json:"system"`
+	RunningCount   int      `json:"runningCount"`
+	TrueCount      *float64 `json:"trueCount,omitempty"`
+	DecksRemaining float64  `json:"decksRemaining"`
+	CardsSeen      int      `json:"cardsSeen"`
+}
+
+type IndexPlay struct {
+	Name      string
+	HandValue int
+	Pair      bool
+	UpCard    int
+	Index     float64
+	H17Index  *float64
+	AtOrAbove string
+	Below     string
+}
+
+// Package Variables
+
+var CountingSystems = map[string]CountingSystem{
+	"HI_LO": {
+		Name:     "HI_LO",
+		Tags:     map[byte]int{'2': 1, '3': 1, '4': 1, '5': 1, '6': 1, 'T': -1, 'J': -1, 'Q': -1, 'K': -1, 'A': -1},
+		Balanced: true,
+	},
+	"KO": {
+		Name:     "KO",
+		Tags:     map[byte]int{'2': 1, '3': 1, '4': 1, '5': 1, '6': 1, '7': 1, 'T': -1, 'J': -1, 'Q': -1, 'K': -1, 'A': -1},
+		Balanced: false,
+	},
+	"OMEGA_II": {
+		Name:     "OMEGA_II",
+		Tags:     map[byte]int{'2': 1, '3': 1, '4': 2, '5': 2, '6': 2, '7': 1, '9': -1, 'T': -2, 'J': -2, 'Q': -2, 'K': -2},
+		Balanced: true,
+	},
+	"ZEN": {
+		Name:     "ZEN",
+		Tags:     map[byte]int{'2': 1, '3': 1, '4': 2, '5': 2, '6': 2, '7': 1, 'T': -2, 'J': -2, 'Q': -2, 'K': -2, 'A': -1},
+		Balanced: true,
+	},
+}
+
+// Hi-Lo index for insurance, the first of the Illustrious 18
+const InsuranceIndex = 3
+
+// Hitting soft 17 makes the dealer ace stronger, so 11 vs A doubles at a lower count
+var elevenVsAceH17Index = -1.0
+
+// Hi-Lo indices for the rest of the Illustrious 18
+var IllustriousEighteen = []IndexPlay{
+	{Name: "16 vs 10", HandValue: 16, UpCard: 10, Index: 0, AtOrAbove: "STAND", Below: "HIT"},
+	{Name: "15 vs 10", HandValue: 15, UpCard: 10, Index: 4, AtOrAbove: "STAND", Below: "HIT"},
+	{Name: "10,10 vs 5", HandValue: 20, Pair: true, UpCard: 5, Index: 5, AtOrAbove: "SPLIT", Below: "STAND"},
+	{Name: "10,10 vs 6", HandValue: 20, Pair: true, UpCard: 6, Index: 4, AtOrAbove: "SPLIT", Below: "STAND"},
+	{Name: "10 vs 10", HandValue: 10, UpCard: 10, Index: 4, AtOrAbove: "DOUBLE", Below: "HIT"},
+	{Name: "12 vs 3", HandValue: 12, UpCard: 3, Index: 2, AtOrAbove: "STAND", Below: "HIT"},
+	{Name: "12 vs 2", HandValue: 12, UpCard: 2, Index: 3, AtOrAbove: "STAND", Below: "HIT"},
+	{Name: "11 vs A", HandValue: 11, UpCard: 11, Index: 1, H17Index: &elevenVsAceH17Index, AtOrAbove: "DOUBLE", Below: "HIT"},
+	{Name: "9 vs 2", HandValue: 9, UpCard: 2, Index: 1, AtOrAbove: "DOUBLE", Below: "HIT"},
+	{Name: "10 vs A", HandValue: 10, UpCard: 11, Index: 4, AtOrAbove: "DOUBLE", Below: "HIT"},
+	{Name: "9 vs 7", HandValue: 9, UpCard: 7, Index: 3, AtOrAbove: "DOUBLE", Below: "HIT"},
+	{Name: "16 vs 9", HandValue: 16, UpCard: 9, Index: 5, AtOrAbove: "STAND", Below: "HIT"},
+	{Name: "13 vs 2", HandValue: 13, UpCard: 2, Index: -1, AtOrAbove: "STAND", Below: "HIT"},
+	{Name: "12 vs 4", HandValue: 12, UpCard: 4, Index: 0, AtOrAbove: "STAND", Below: "HIT"},
+	{Name: "12 vs 5", HandValue: 12, UpCard: 5, Index: -2, AtOrAbove: "STAND", Below: "HIT"},
+	{Name: "12 vs 6", HandValue: 12, UpCard: 6, Index: -1, AtOrAbove: "STAND", Below: "HIT"},
+	{Name: "13 vs 3", HandValue: 13, UpCard: 3, Index: -2, AtOrAbove: "STAND", Below: "HIT"},
+}
+
+// Hi-Lo indices for the Fab 4 late surrender deviations
+var FabFour = []IndexPlay{
+	{Name: "14 vs 10", HandValue: 14, UpCard: 10, Index: 3, AtOrAbove: "SURRENDER"},
+	{Name: "15 vs 10", HandValue: 15, UpCard: 10, Index: 0, AtOrAbove: "SURRENDER"},
+	{Name: "15 vs 9", HandValue: 15, UpCard: 9, Index: 2, AtOrAbove: "SURRENDER"},
+	{Name: "15 vs A", HandValue: 15, UpCard: 11, Index: 1, AtOrAbove: "SURRENDER"},
+}
+
+// Functions
+
+func (system CountingSystem) InitialRunningCount(decks int) int {
+	// KO starts below zero so its pivot lands on +4 instead of needing a true count conversion
+	if system.Name == "KO" {
+		return 4 - 4*decks
+	}
+	return 0
+}
+
+func (play IndexPlay) IndexForRules(rules BlackjackRules) float64 {
+	if rules.DealerHitsSoft17 && play.H17Index != nil {
+		return *play.H17Index
+	}
+	return play.Index
+}
+
+func CalculateCount(system CountingSystem, shoe *Shoe) CountResponseBody {
+	// Cards set on the shoe are dealt ahead of the shuffled ones and count the same
+	seenCards := append(append([]Card{}, shoe.SequencedDealt...), shoe.Cards[:shoe.NextCard]...)
+
+	runningCount := system.InitialRunningCount(shoe.NumDecks)
+	for _, card := range seenCards {
+		runningCount += system.Tags[card.RankLabel[0]]
+	}
+
+	// Set cards come out of the decks remaining as well
+	cardsLeft := shoe.CardsLeft() - len(shoe.SequencedDealt)
+	if cardsLeft < 0 {
+		cardsLeft = 0
+	}
+	decksRemaining := float64(cardsLeft) / 52
+	count := CountResponseBody{
+		System:         system.Name,
+		RunningCount:   runningCount,
+		DecksRemaining: decksRemaining,
+		CardsSeen:      len(seenCards),
+	}
+
+	if system.Balanced && decksRemaining > 0 {
+		trueCount := float64(runningCount) / decksRemaining
+		count.TrueCount = &trueCount
+	}
+
+	return count
+}
+
+func CalculateCountingStrategyDecision(cards []string, upCard string, rules BlackjackRules, trueCount float64) (string, string) {
+	// Index plays are keyed on hard totals, so multi-card hands like T-2-A have to be recognised as hard
+	isSoft := IsSoftTotal(cards)
+	baseDecision := CalculateStrategyDecisionForHand(cards, upCard, rules, isSoft)
+	if len(cards) < 2 || isSoft {
+		return baseDecision, ""
+	}
+
+	handValue := CalculateBlackjackValueForCards(cards)
+	dealerUpCardValue := CalculateBlackjackValueForCard(upCard)
+	isPair := len(cards) == 2 && cards[0][0] == cards[1][0]
+	isTenPair := isPair && handValue == 20
+	if isPair && !isTenPair {
+		return baseDecision, ""
+	}
+
+	if rules.Surrender != SurrenderNone && len(cards) == 2 && !isTenPair {
+		for _, play := range FabFour {
+			if play.HandValue != handValue || play.UpCard != dealerUpCardValue {
+				continue
+			}
+			if trueCount >= play.Index {
+				return play.AtOrAbove, play.Name
+			}
+			// Below the index the hand is played as if surrender were not offered
+			noSurrenderRules := rules
+			noSurrenderRules.Surrender = SurrenderNone
+			baseDecision = CalculateStrategyDecisionForHand(cards, upCard, noSurrenderRules, isSoft)
+		}
+	}
+
+	if baseDecision == "SURRENDER" {
+		return baseDecision, ""
+	}
+
+	for _, play := range IllustriousEighteen {
+		if play.HandValue != handValue || play.UpCard != dealerUpCardValue || play.Pair != isTenPair {
+			continue
+		}
+
+		decision := play.Below
+		if trueCount >= play.IndexForRules(rules) {
+			decision = play.AtOrAbove
+		}
+		if (decision == "DOUBLE" && len(cards) != 2) || (decision == "SPLIT" && rules.MaxSplits < 1) {
+			return baseDecision, ""
+		}
+		// The indices assume a peeking dealer, without one the extra money is lost to every dealer blackjack
+		if !rules.DealerPeeks && dealerUpCardValue >= 10 && (decision == "DOUBLE" || decision == "SPLIT") {
+			return baseDecision, ""
+		}
+		return decision, play.Name
+	}
+
+	return baseDecision, ""
+}
+
+// Handlers
+
+func GetShoeCountHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	shoeID := vars["id"]
+
+	systemName := r.URL.Query().Get("system")
+	if systemName == "" {
+		systemName = "HI_LO"
+	}
+	system, systemFound := CountingSystems[systemName]
+	if !systemFound {
+		http.Error(w, fmt.Sprintf("Unknown counting system %q", systemName), http.StatusBadRequest)
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	shoe, found := FindShoe(shoeID)
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	json.NewEncoder(w).Encode(CalculateCount(system, shoe))
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCardCounting(t *testing.T) {
+	shoe := NewShoeWithSource(1, NewSeededSource(1))
+
+	// Deal every 2 through 6, the four sevens and two eights off the top
+	var low, high []Card
+	eights := 0
+	for _, card := range shoe.Cards {
+		switch {
+		case card.RankLabel >= "2" && card.RankLabel <= "7":
+			low = append(low, card)
+		case card.RankLabel == "8" && eights < 2:
+			low = append(low, card)
+			eights++
+		default:
+			high = append(high, card)
+		}
+	}
+	shoe.Cards = append(low, high...)
+	for i := 0; i < 26; i++ {
+		shoe.DrawCard()
+	}
+
+	count := CalculateCount(CountingSystems["HI_LO"], shoe)
+	if count.RunningCount != 20 || count.CardsSeen != 26 || count.DecksRemaining != 0.5 {
+		t.Errorf("hi-lo running count expected to be 20 after 26 cards")
+	}
+	if count.TrueCount == nil || *count.TrueCount != 40 {
+		t.Errorf("hi-lo true count expected to be 40 with half a deck left")
+	}
+
+	count = CalculateCount(CountingSystems["KO"], shoe)
+	if count.RunningCount != 24 || count.TrueCount != nil {
+		t.Errorf("ko expected a running count of 24 and no true count")
+	}
+
+	if CalculateCount(CountingSystems["OMEGA_II"], shoe).RunningCount != 36 {
+		t.Errorf("omega ii running count expected to be 36")
+	}
+	if CalculateCount(CountingSystems["ZEN"], shoe).RunningCount != 36 {
+		t.Errorf("zen running count expected to be 36")
+	}
+
+	if CountingSystems["KO"].InitialRunningCount(6) != -20 {
+		t.Errorf("ko initial running count expected to be -20 for six decks")
+	}
+
+	shoe.Reset()
+	if CalculateCount(CountingSystems["HI_LO"], shoe).RunningCount != 0 {
+		t.Errorf("running count expected to reset with the shoe")
+	}
+
+	shoe.SequencedCards = MakeCardsFromStrings([]string{"5S", "6H", "2D"})
+	for i := 0; i < 3; i++ {
+		shoe.DrawCard()
+	}
+	count = CalculateCount(CountingSystems["HI_LO"], shoe)
+	if count.RunningCount != 3 || count.CardsSeen != 3 || count.DecksRemaining != 49.0/52 {
+		t.Errorf("cards set on the shoe expected to be counted and taken out of the decks remaining")
+	}
+}
+
+func TestCountingDeviations(t *testing.T) {
+	noSurrender := LegacyStrategyRules()
+	lateSurrender := DefaultBlackjackRules()
+
+	decision, play := CalculateCountingStrategyDecision([]string{"T", "6"}, "TS", noSurrender, 0)
+	if decision != "STAND" || play != "16 vs 10" {
+		t.Errorf("16 vs 10 expected to stand at a true count of 0")
+	}
+	decision, _ = CalculateCountingStrategyDecision([]string{"T", "6"}, "TS", noSurrender, -1)
+	if decision != "HIT" {
+		t.Errorf("16 vs 10 expected to hit below a true count of 0")
+	}
+
+	decision, _ = CalculateCountingStrategyDecision([]string{"T", "2"}, "2S", noSurrender, 3)
+	if decision != "STAND" {
+		t.Errorf("12 vs 2 expected to stand at a true count of 3")
+	}
+	decision, _ = CalculateCountingStrategyDecision([]string{"T", "2"}, "2S", noSurrender, 2)
+	if decision != "HIT" {
+		t.Errorf("12 vs 2 expected to hit below a true count of 3")
+	}
+
+	decision, _ = CalculateCountingStrategyDecision([]string{"T", "T"}, "6S", noSurrender, 4)
+	if decision != "SPLIT" {
+		t.Errorf("10,10 vs 6 expected to split at a true count of 4")
+	}
+	decision, _ = CalculateCountingStrategyDecision([]string{"T", "T"}, "6S", noSurrender, 3)
+	if decision != "STAND" {
+		t.Errorf("10,10 vs 6 expected to stand below a true count of 4")
+	}
+
+	decision, _ = CalculateCountingStrategyDecision([]string{"6", "5"}, "AS", noSurrender, 1)
+	if decision != "DOUBLE" {
+		t.Errorf("11 vs A expected to double at a true count of 1")
+	}
+	decision, _ = CalculateCountingStrategyDecision([]string{"3", "3", "5"}, "AS", noSurrender, 1)
+	if decision == "DOUBLE" {
+		t.Errorf("three-card 11 vs A cannot double")
+	}
+
+	decision, play = CalculateCountingStrategyDecision([]string{"T", "5"}, "TS", lateSurrender, 0)
+	if decision != "SURRENDER" || play != "15 vs 10" {
+		t.Errorf("15 vs 10 expected to surrender at a true count of 0")
+	}
+	decision, _ = CalculateCountingStrategyDecision([]string{"T", "5"}, "TS", lateSurrender, -1)
+	if decision != "HIT" {
+		t.Errorf("15 vs 10 expected to hit below a true count of 0")
+	}
+
+	decision, _ = CalculateCountingStrategyDecision([]string{"T", "4"}, "TS", lateSurrender, 3)
+	if decision != "SURRENDER" {
+		t.Errorf("14 vs 10 expected to surrender at a true count of 3")
+	}
+	decision, _ = CalculateCountingStrategyDecision([]string{"T", "4"}, "TS", lateSurrender, 2)
+	if decision != "HIT" {
+		t.Errorf("14 vs 10 expected to hit below a true count of 3")
+	}
+
+	decision, _ = CalculateCountingStrategyDecision([]string{"T", "5"}, "9S", lateSurrender, 2)
+	if decision != "SURRENDER" {
+		t.Errorf("15 vs 9 expected to surrender at a true count of 2")
+	}
+	decision, _ = CalculateCountingStrategyDecision([]string{"T", "5"}, "AS", lateSurrender, 0)
+	if decision == "SURRENDER" {
+		t.Errorf("15 vs A expected not to surrender below a true count of 1")
+	}
+
+	h17 := LegacyStrategyRules()
+	h17.DealerHitsSoft17 = true
+	decision, _ = CalculateCountingStrategyDecision([]string{"6", "5"}, "AS", h17, 0)
+	if decision != "DOUBLE" {
+		t.Errorf("11 vs A expected to double at a true count of 0 when the dealer hits soft 17")
+	}
+	decision, _ = CalculateCountingStrategyDecision([]string{"6", "5"}, "AS", h17, -2)
+	if decision != "HIT" {
+		t.Errorf("11 vs A expected to hit below a true count of -1 when the dealer hits soft 17")
+	}
+
+	decision, play = CalculateCountingStrategyDecision([]string{"9", "2", "A"}, "4S", noSurrender, 1)
+	if decision != "STAND" || play != "12 vs 4" {
+		t.Errorf("hard 12 with an ace vs 4 expected to take the index play")
+	}
+
+	enhc := LegacyStrategyRules()
+	enhc.DealerPeeks = false
+	decision, play = CalculateCountingStrategyDecision([]string{"6", "4"}, "TS", enhc, 10)
+	if decision != "HIT" || play != "" {
+		t.Errorf("10 vs 10 not expected to double without a hole card")
+	}
+
+	recorder := httptest.NewRecorder()
+	GetBlackjackStrategyHandler(recorder, httptest.NewRequest("POST", "/blackjack/strategy", strings.NewReader(`{"cards":["T","5"],"upCard":"TS","trueCount":0}`)))
+	if strings.TrimSpace(recorder.Body.String()) != `"HIT"` {
+		t.Errorf("omitted rules expected to keep the legacy chart without surrender")
+	}
+
+	decision, play = CalculateCountingStrategyDecision([]string{"A", "6"}, "3S", noSurrender, 10)
+	if decision != CalculateStrategyDecision([]string{"A", "6"}, "3S") || play != "" {
+		t.Errorf("soft hands expected to follow basic strategy")
+	}
+}
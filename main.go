@@ -1,9 +1,12 @@
 package main
 
 import (
+	"crypto/hmac"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"math/rand"
 	"net/http"
 	"strconv"
 	"strings"
@@ -18,8 +21,17 @@ import (
 // Structs
 
 type StrategyBody struct {
-	Cards  []string `json:"cards"`
-	UpCard string   `json:"upCard"`
+	Cards     []string        `json:"cards"`
+	UpCard    string          `json:"upCard"`
+	Rules     *BlackjackRules `json:"rules"`
+	ShoeSize  int             `json:"shoeSize"`
+	TrueCount *float64        `json:"trueCount"`
+}
+
+type StrategyResponseBody struct {
+	Decision string `json:"decision"`
+	Basis    string `json:"basis"`
+	Decks    int    `json:"decks"`
 }
 
 type MapRuneBoolResponseBody struct {
@@ -75,7 +87,63 @@ type Deck struct {
 }
 
 type Shoe struct {
-	Decks []*Deck
+	ID             string
+	NumDecks       int
+	Penetration    float64
+	Cards          []Card
+	NextCard       int
+	SequencedCards []Card
+	SequencedDealt []Card
+	Source         RandomSource
+	Shuffles       []ShuffleRecord
+	ProvablyFair   bool
+	ClientSeed     string
+	PendingShuffle bool
+}
+
+type ShuffleRecord struct {
+	ShoeID     string    `json:"shoeId"`
+	Number     int       `json:"number"`
+	Decks      int       `json:"decks"`
+	RNG        string    `json:"rng"`
+	Seed       string    `json:"seed,omitempty"`
+	Commitment string    `json:"commitment,omitempty"`
+	ClientSeed string    `json:"clientSeed,omitempty"`
+	OrderHash  string    `json:"orderHash,omitempty"`
+	Voided     bool      `json:"voided,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+type ShuffleVerificationResponseBody struct {
+	Verified bool     `json:"verified"`
+	Reason   string   `json:"reason,omitempty"`
+	Cards    []string `json:"cards,omitempty"`
+}
+
+type ShoeBody struct {
+	Decks        int     `json:"decks"`
+	Penetration  float64 `json:"penetration"`
+	RNG          string  `json:"rng"`
+	Seed         *int64  `json:"seed"`
+	ProvablyFair bool    `json:"provablyFair"`
+	ClientSeed   string  `json:"clientSeed"`
+}
+
+type ClientSeedBody struct {
+	ClientSeed string `json:"clientSeed"`
+}
+
+type ShoeResponseBody struct {
+	ID           string  `json:"id"`
+	Decks        int     `json:"decks"`
+	Penetration  float64 `json:"penetration"`
+	CardsLeft    int     `json:"cardsLeft"`
+	NeedsShuffle bool    `json:"needsShuffle"`
+	RNG          string  `json:"rng"`
+	Seed         *int64  `json:"seed,omitempty"`
+	ProvablyFair bool    `json:"provablyFair"`
+	Commitment   string  `json:"commitment,omitempty"`
+	ClientSeed   string  `json:"clientSeed,omitempty"`
 }
 
 // Package Variables
@@ -83,11 +151,18 @@ type Shoe struct {
 var ranks []Rank
 var suits []Suit
 var pokerHandTypes []PokerHandType
-var SizeToShoeMap = make(map[int]*Shoe)
-var SizeToSequencedCardsMap = make(map[int][]Card)
+var Shoes = make(map[string]*Shoe)
 var SequencedRolls []int
+var DiceSource RandomSource = CryptoSource{}
 var mutex = &sync.Mutex{}
 
+const DefaultPenetration = 0.75
+const MaxDecksPerShoe = 10
+const NeedsShuffleHeader = "X-Needs-Shuffle"
+
+const StrategyBasisTotal = "TOTAL_DEPENDENT"
+const StrategyBasisComposition = "COMPOSITION_DEPENDENT"
+
 // Functions
 
 func NewDeck() *Deck {
@@ -103,23 +178,7 @@ func NewDeck() *Deck {
 }
 
 func (s *Shoe) CardsLeft() int {
-	totalCards := 0
-	for _, deck := range s.Decks {
-		totalCards += len(deck.Cards)
-	}
-	return totalCards
-}
-
-func (d *Deck) DrawCard() Card {
-	if len(d.Cards) == 0 {
-		panic("deck is empty")
-	}
-
-	rand.Seed(time.Now().UnixNano())
-	index := rand.Intn(len(d.Cards))
-	drawnCard := d.Cards[index]
-	d.Cards = append(d.Cards[:index], d.Cards[index+1:]...)
-	return drawnCard
+	return len(s.Cards) - s.NextCard
 }
 
 func (d *Deck) HasCards() bool {
@@ -141,51 +200,238 @@ func (d *Deck) String() string {
 }
 
 func NewShoe(numDecks int) *Shoe {
-	var decks []*Deck
-	for i := 0; i < numDecks; i++ {
-		decks = append(decks, NewDeck())
+	return NewShoeWithSource(numDecks, CryptoSource{})
+}
+
+func NewShoeWithSource(numDecks int, source RandomSource) *Shoe {
+	shoe := &Shoe{NumDecks: numDecks, Penetration: DefaultPenetration, Source: source}
+	shoe.Reset()
+	return shoe
+}
+
+func (s *Shoe) Reset() {
+	var cards []Card
+	for i := 0; i < s.NumDecks; i++ {
+		cards = append(cards, NewDeck().Cards...)
+	}
+	s.Cards = cards
+	s.NextCard = 0
+	s.SequencedDealt = nil
+	s.Shuffle()
+}
+
+func (s *Shoe) Shuffle() {
+	if s.ProvablyFair {
+		s.CommitShuffle()
+		return
+	}
+
+	var shuffleSource RandomSource
+	var rngMode, seed string
+
+	switch source := s.Source.(type) {
+	case *SeededSource:
+		// A seeded shoe takes a fresh seed from its stream on every reshuffle so each shuffle can be replayed on its own
+		if len(s.Shuffles) > 0 {
+			source = NewSeededSource(source.Int63())
+			s.Source = source
+		}
+		shuffleSource = source
+		rngMode = RNGModeSeeded
+		seed = strconv.FormatInt(source.Seed, 10)
+	default:
+		// Crypto shoes shuffle from a fresh random seed so the order can be replayed once the seed is revealed
+		hashSource := NewHashSource(NewRandomSeedBytes())
+		shuffleSource = hashSource
+		rngMode = RNGModeCrypto
+		seed = hex.EncodeToString(hashSource.Seed)
+	}
+
+	ShuffleCards(s.Cards, shuffleSource)
+
+	s.Shuffles = append(s.Shuffles, ShuffleRecord{
+		ShoeID:    s.ID,
+		Number:    s.NextShuffleNumber(),
+		Decks:     s.NumDecks,
+		RNG:       rngMode,
+		Seed:      seed,
+		OrderHash: HashCardOrder(s.Cards),
+		Timestamp: time.Now().UTC(),
+	})
+}
+
+func (s *Shoe) NextShuffleNumber() int {
+	// Numbers keep counting up when old records have been pruned from the log
+	if len(s.Shuffles) == 0 {
+		return 1
+	}
+	return s.Shuffles[len(s.Shuffles)-1].Number + 1
+}
+
+func (s *Shoe) CommitShuffle() {
+	// The cards are only shuffled at the first draw, once the client has had a chance to supply its seed
+	serverSeed := NewRandomSeedBytes()
+	commitment := sha256.Sum256(serverSeed)
+	// A commitment that never reached its first draw has no card order to verify
+	if s.PendingShuffle {
+		s.Shuffles[len(s.Shuffles)-1].Voided = true
+	}
+	s.PendingShuffle = true
+
+	s.Shuffles = append(s.Shuffles, ShuffleRecord{
+		ShoeID:     s.ID,
+		Number:     s.NextShuffleNumber(),
+		Decks:      s.NumDecks,
+		RNG:        RNGModeProvablyFair,
+		Seed:       hex.EncodeToString(serverSeed),
+		Commitment: hex.EncodeToString(commitment[:]),
+		Timestamp:  time.Now().UTC(),
+	})
+}
+
+func (s *Shoe) ApplyPendingShuffle() {
+	record := &s.Shuffles[len(s.Shuffles)-1]
+	serverSeed, _ := hex.DecodeString(record.Seed)
+
+	record.ClientSeed = s.ClientSeed
+	ShuffleCards(s.Cards, NewHashSource(CombineSeeds(serverSeed, s.ClientSeed)))
+	record.OrderHash = HashCardOrder(s.Cards)
+	s.PendingShuffle = false
+}
+
+func CombineSeeds(serverSeed []byte, clientSeed string) []byte {
+	mac := hmac.New(sha256.New, serverSeed)
+	mac.Write([]byte(clientSeed))
+	return mac.Sum(nil)
+}
+
+func ShuffleCards(cards []Card, source RandomSource) {
+	// Fisher-Yates over every card in the shoe
+	for i := len(cards) - 1; i > 0; i-- {
+		j := source.Intn(i + 1)
+		cards[i], cards[j] = cards[j], cards[i]
+	}
+}
+
+func HashCardOrder(cards []Card) string {
+	var cardStrings []string
+	for _, card := range cards {
+		cardStrings = append(cardStrings, card.String())
 	}
-	return &Shoe{Decks: decks}
+	orderHash := sha256.Sum256([]byte(strings.Join(cardStrings, ",")))
+	return hex.EncodeToString(orderHash[:])
+}
+
+func ReplayShuffle(record ShuffleRecord) ([]Card, error) {
+	if record.Voided {
+		return nil, fmt.Errorf("shuffle %d was voided before any card was dealt", record.Number)
+	}
+
+	var source RandomSource
+	switch record.RNG {
+	case RNGModeSeeded:
+		seed, err := strconv.ParseInt(record.Seed, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid seed %q", record.Seed)
+		}
+		source = NewSeededSource(seed)
+	case RNGModeCrypto:
+		seed, err := hex.DecodeString(record.Seed)
+		if err != nil || len(seed) == 0 {
+			return nil, fmt.Errorf("invalid seed %q", record.Seed)
+		}
+		source = NewHashSource(seed)
+	case RNGModeProvablyFair:
+		serverSeed, err := hex.DecodeString(record.Seed)
+		if err != nil || len(serverSeed) == 0 {
+			return nil, fmt.Errorf("invalid seed %q", record.Seed)
+		}
+		commitment := sha256.Sum256(serverSeed)
+		if hex.EncodeToString(commitment[:]) != record.Commitment {
+			return nil, fmt.Errorf("server seed does not match the commitment of shuffle %d", record.Number)
+		}
+		source = NewHashSource(CombineSeeds(serverSeed, record.ClientSeed))
+	default:
+		return nil, fmt.Errorf("unknown rng %q", record.RNG)
+	}
+
+	var cards []Card
+	for i := 0; i < record.Decks; i++ {
+		cards = append(cards, NewDeck().Cards...)
+	}
+	ShuffleCards(cards, source)
+
+	if HashCardOrder(cards) != record.OrderHash {
+		return nil, fmt.Errorf("replayed order does not match shuffle %d", record.Number)
+	}
+	return cards, nil
+}
+
+func MakeShuffleRecordsResponseBody(shoe *Shoe) []ShuffleRecord {
+	records := append([]ShuffleRecord{}, shoe.Shuffles...)
+
+	// The seed of a crypto shoe's current shuffle stays hidden until it is retired, otherwise the remaining cards could be predicted
+	lastIndex := len(records) - 1
+	if lastIndex >= 0 && records[lastIndex].RNG != RNGModeSeeded {
+		records[lastIndex].Seed = ""
+	}
+	return records
+}
+
+func (s *Shoe) RemainingCards() []Card {
+	return append([]Card{}, s.Cards[s.NextCard:]...)
+}
+
+func (s *Shoe) CutCardPosition() int {
+	return int(s.Penetration * float64(s.NumDecks*52))
+}
+
+func (s *Shoe) NeedsShuffle() bool {
+	return s.NumDecks*52-s.CardsLeft() >= s.CutCardPosition()
+}
+
+func (s *Shoe) EndRound() bool {
+	if !s.NeedsShuffle() {
+		return false
+	}
+	s.Reset()
+	return true
 }
 
 func (s *Shoe) DrawCard() Card {
-	if len(s.Decks) == 0 {
+	if s.NumDecks == 0 {
 		panic("shoe has no decks")
 	}
 
-	shoeSize := len(s.Decks)
-	sequencedCards := SizeToSequencedCardsMap[shoeSize]
-	if len(sequencedCards) == 0 {
-		// Choose a random deck from the shoe
-		rand.Seed(time.Now().UnixNano())
-		deckIndex := rand.Intn(len(s.Decks))
-		if s.Decks[deckIndex].HasCards() {
-			return s.Decks[deckIndex].DrawCard()
-		} else {
-			ResetShoe(shoeSize)
-			return SizeToShoeMap[shoeSize].DrawCard()
+	if len(s.SequencedCards) == 0 {
+		// Running out of cards mid-round is the only time the dealer reshuffles without waiting for the end of the round
+		if !s.HasCards() {
+			s.Reset()
+		}
+		if s.PendingShuffle {
+			s.ApplyPendingShuffle()
 		}
 
+		drawnCard := s.Cards[s.NextCard]
+		s.NextCard++
+		return drawnCard
+
 	} else {
-		drawnSequencedCard := sequencedCards[0]
-		SizeToSequencedCardsMap[shoeSize] = sequencedCards[1:]
+		drawnSequencedCard := s.SequencedCards[0]
+		s.SequencedCards = s.SequencedCards[1:]
+		s.SequencedDealt = append(s.SequencedDealt, drawnSequencedCard)
 		return drawnSequencedCard
 	}
 }
 
 func (s *Shoe) HasCards() bool {
-	for _, deck := range s.Decks {
-		if deck.HasCards() {
-			return true
-		}
-	}
-	return false
+	return s.CardsLeft() > 0
 }
 
 func (s *Shoe) String() string {
 	var result string
-	for i, deck := range s.Decks {
-		result += fmt.Sprintf("Deck %d:\n%s", i+1, deck)
+	for _, card := range s.RemainingCards() {
+		result += fmt.Sprintf("%s%s\n", card.RankLabel, card.Suit)
 	}
 	return result
 }
@@ -313,22 +559,128 @@ func MakeCardsFromStrings(cardStrings []string) []Card {
 	return cards
 }
 
-func SetCardsInShoe(shoeSize int, cards []string) bool {
-	// fmt.Println("SetCardsInShoe called with shoeSize = " + strconv.Itoa(shoeSize))
-	// fmt.Println("cards = ")
-	// fmt.Println(cards)
-	cardStructs := MakeCardsFromStrings(cards)
+func NewRandomID() string {
+	idBytes := make([]byte, 8)
+	cryptorand.Read(idBytes)
+	return hex.EncodeToString(idBytes)
+}
+
+func NewShoeID() string {
+	for {
+		id := NewRandomID()
 
-	SizeToSequencedCardsMap[shoeSize] = cardStructs
+		// Numeric IDs are reserved for the legacy shoes keyed by deck count
+		_, numericErr := strconv.Atoi(id)
+		if _, found := Shoes[id]; !found && numericErr != nil {
+			return id
+		}
+	}
+}
 
+func RegisterShoe(id string, shoe *Shoe) *Shoe {
+	shoe.ID = id
+	for i := range shoe.Shuffles {
+		shoe.Shuffles[i].ShoeID = id
+	}
+	Shoes[id] = shoe
+	return shoe
+}
+
+func CreateShoe(shoeBody ShoeBody) *Shoe {
+	shoe := &Shoe{
+		NumDecks:     shoeBody.Decks,
+		Penetration:  shoeBody.Penetration,
+		Source:       NewRandomSource(RNGBody{Mode: shoeBody.RNG, Seed: shoeBody.Seed}),
+		ProvablyFair: shoeBody.ProvablyFair,
+		ClientSeed:   shoeBody.ClientSeed,
+	}
+	shoe.Reset()
+	return RegisterShoe(NewShoeID(), shoe)
+}
+
+func FindShoe(id string) (*Shoe, bool) {
+	shoe, found := Shoes[id]
+	return shoe, found
+}
+
+func DeleteShoe(id string) bool {
+	if _, found := Shoes[id]; !found {
+		return false
+	}
+	delete(Shoes, id)
+	delete(BaccaratHistories, id)
+	return true
+}
+
+func SetCardsInShoe(id string, cards []string) bool {
+	shoe, found := FindShoe(id)
+	if !found {
+		return false
+	}
+
+	shoe.SequencedCards = MakeCardsFromStrings(cards)
 	return true
 }
 
-func ResetShoe(shoeSize int) bool {
-	SizeToShoeMap[shoeSize] = NewShoe(shoeSize)
+func ResetShoe(id string) bool {
+	shoe, found := FindShoe(id)
+	if !found {
+		return false
+	}
+
+	shoe.Reset()
 	return true
 }
 
+func ValidateShoeBody(shoeBody *ShoeBody) error {
+	if shoeBody.Decks < 1 || shoeBody.Decks > MaxDecksPerShoe {
+		return fmt.Errorf("decks must be between 1 and %d", MaxDecksPerShoe)
+	}
+	if shoeBody.Penetration == 0 {
+		shoeBody.Penetration = DefaultPenetration
+	}
+	if shoeBody.Penetration <= 0 || shoeBody.Penetration > 1 {
+		return fmt.Errorf("penetration must be greater than 0 and at most 1")
+	}
+
+	rngBody := RNGBody{Mode: shoeBody.RNG, Seed: shoeBody.Seed}
+	if err := ValidateRNGBody(&rngBody); err != nil {
+		return err
+	}
+	shoeBody.RNG = rngBody.Mode
+	shoeBody.Seed = rngBody.Seed
+
+	if shoeBody.ProvablyFair && shoeBody.RNG != RNGModeCrypto {
+		return fmt.Errorf("provably fair shoes must use the %q rng", RNGModeCrypto)
+	}
+	if !shoeBody.ProvablyFair && shoeBody.ClientSeed != "" {
+		return fmt.Errorf("a client seed can only be used with a provably fair shoe")
+	}
+	return nil
+}
+
+func MakeShoeResponseBody(shoe *Shoe) ShoeResponseBody {
+	rngResponseBody := MakeRNGResponseBody(shoe.Source)
+
+	var commitment string
+	if shoe.ProvablyFair {
+		commitment = shoe.Shuffles[len(shoe.Shuffles)-1].Commitment
+	}
+
+	return ShoeResponseBody{
+		ID:           shoe.ID,
+		Decks:        shoe.NumDecks,
+		Penetration:  shoe.Penetration,
+		CardsLeft:    shoe.CardsLeft(),
+		NeedsShuffle: shoe.NeedsShuffle(),
+		RNG:          rngResponseBody.Mode,
+		Seed:         rngResponseBody.Seed,
+		ProvablyFair: shoe.ProvablyFair,
+		Commitment:   commitment,
+		ClientSeed:   shoe.ClientSeed,
+	}
+}
+
 func FindOrderForRank(rank string) int {
 	for _, item := range ranks {
 		if item.Label == rank {
@@ -524,18 +876,12 @@ func DrawCardHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	vars := mux.Vars(r)
-	shoeSizeStr := vars["shoeSize"]
-
-	shoeSize, err := strconv.Atoi(shoeSizeStr)
-	if err != nil {
-		http.Error(w, "Invalid shoe size", http.StatusBadRequest)
-		return
-	}
+	shoeID := vars["id"]
 
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	shoe, found := SizeToShoeMap[shoeSize]
+	shoe, found := FindShoe(shoeID)
 	if !found {
 		http.NotFound(w, r)
 		return
@@ -543,6 +889,8 @@ func DrawCardHandler(w http.ResponseWriter, r *http.Request) {
 
 	drawnCard := shoe.DrawCard()
 	// fmt.Println("DrawCardHandler has drawn a card: " + drawnCard.String())
+	// The body stays a bare card string, so the cut card is flagged in a header as well as on GET /shoes/{id}
+	w.Header().Set(NeedsShuffleHeader, strconv.FormatBool(shoe.NeedsShuffle()))
 	json.NewEncoder(w).Encode(drawnCard.String())
 	return
 }
@@ -551,15 +899,12 @@ func CardsLeftHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	vars := mux.Vars(r)
-	shoeSizeStr := vars["shoeSize"]
+	shoeID := vars["id"]
 
-	shoeSize, err := strconv.Atoi(shoeSizeStr)
-	if err != nil {
-		http.Error(w, "Invalid shoe size", http.StatusBadRequest)
-		return
-	}
+	mutex.Lock()
+	defer mutex.Unlock()
 
-	shoe, found := SizeToShoeMap[shoeSize]
+	shoe, found := FindShoe(shoeID)
 	if !found {
 		http.NotFound(w, r)
 		return
@@ -697,28 +1042,79 @@ func GetRankComparisonHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func CalculateStrategyDecision(cards []string, upCard string) string {
+	return CalculateStrategyDecisionForRules(cards, upCard, LegacyStrategyRules())
+}
+
+func CalculateShouldSurrender(cards []string, dealerUpCardValue int, rules BlackjackRules) bool {
+	if len(cards) != 2 || CalculateIsSoft(cards) {
+		return false
+	}
+
+	handValue := CalculateBlackjackValueForCards(cards)
+	isEights := cards[0][0] == '8' && cards[1][0] == '8'
+
+	switch rules.Surrender {
+	case SurrenderEarly:
+		switch dealerUpCardValue {
+		case 11:
+			return (handValue >= 5 && handValue <= 7) || (handValue >= 12 && handValue <= 17)
+		case 10:
+			return handValue >= 14 && handValue <= 16
+		case 9:
+			return handValue == 16 && !isEights
+		}
+	case SurrenderLate:
+		if isEights {
+			return rules.DealerHitsSoft17 && dealerUpCardValue == 11
+		}
+		switch handValue {
+		case 15:
+			return dealerUpCardValue == 10 || (rules.DealerHitsSoft17 && dealerUpCardValue == 11)
+		case 16:
+			return dealerUpCardValue >= 9
+		case 17:
+			return rules.DealerHitsSoft17 && dealerUpCardValue == 11
+		}
+	}
+
+	return false
+}
+
+func CalculateStrategyDecisionForRules(cards []string, upCard string, rules BlackjackRules) string {
+	return CalculateStrategyDecisionForHand(cards, upCard, rules, CalculateIsSoft(cards))
+}
+
+func CalculateStrategyDecisionForHand(cards []string, upCard string, rules BlackjackRules, isSoft bool) string {
 	handValue := CalculateBlackjackValueForCards(cards)
 	dealerUpCardValue := CalculateBlackjackValueForCard(upCard)
 	var result string
 
-	if len(cards) == 2 && cards[0][0] == cards[1][0] && handValue != 10 && handValue != 20 {
+	if CalculateShouldSurrender(cards, dealerUpCardValue, rules) {
+		result = "SURRENDER"
+	} else if len(cards) == 2 && cards[0][0] == cards[1][0] && handValue != 10 && handValue != 20 && rules.MaxSplits > 0 {
 		switch cards[0][0] {
 		case 'A', '8':
 			result = "SPLIT"
-		case '2', '3', '7':
-			if dealerUpCardValue < 8 {
+		case '2', '3':
+			if (rules.DoubleAfterSplit && dealerUpCardValue < 8) || (dealerUpCardValue > 3 && dealerUpCardValue < 8) {
+				result = "SPLIT"
+			} else {
+				result = "HIT"
+			}
+		case '7':
+			if dealerUpCardValue < 8 || (rules.DoubleAfterSplit && rules.Decks == 1 && dealerUpCardValue == 8) {
 				result = "SPLIT"
 			} else {
 				result = "HIT"
 			}
 		case '4':
-			if dealerUpCardValue == 5 || dealerUpCardValue == 6 {
+			if rules.DoubleAfterSplit && (dealerUpCardValue == 5 || dealerUpCardValue == 6) {
 				result = "SPLIT"
 			} else {
 				result = "HIT"
 			}
 		case '6':
-			if dealerUpCardValue < 7 {
+			if (rules.DoubleAfterSplit && dealerUpCardValue < 7) || (dealerUpCardValue > 2 && dealerUpCardValue < 7) || (rules.DoubleAfterSplit && rules.Decks == 1 && dealerUpCardValue == 7) {
 				result = "SPLIT"
 			} else {
 				result = "HIT"
@@ -731,13 +1127,25 @@ func CalculateStrategyDecision(cards []string, upCard string) string {
 				result = "SPLIT"
 			}
 		}
-	} else if CalculateIsSoft(cards) {
+	} else if isSoft {
 		switch handValue {
-		case 19, 20:
+		case 20:
 			result = "STAND"
+		case 19:
+			if (rules.DealerHitsSoft17 || rules.Decks == 1) && dealerUpCardValue == 6 && len(cards) == 2 {
+				result = "DOUBLE"
+			} else {
+				result = "STAND"
+			}
 		case 18:
 			switch dealerUpCardValue {
-			case 2, 7, 8:
+			case 2:
+				if rules.DealerHitsSoft17 && len(cards) == 2 {
+					result = "DOUBLE"
+				} else {
+					result = "STAND"
+				}
+			case 7, 8:
 				result = "STAND"
 			default:
 				if dealerUpCardValue > 2 && dealerUpCardValue < 7 {
@@ -751,7 +1159,7 @@ func CalculateStrategyDecision(cards []string, upCard string) string {
 				}
 			}
 		case 17:
-			if dealerUpCardValue > 2 && dealerUpCardValue < 7 {
+			if (dealerUpCardValue > 2 || rules.Decks == 1) && dealerUpCardValue < 7 {
 				if len(cards) == 2 {
 					result = "DOUBLE"
 				} else {
@@ -771,7 +1179,7 @@ func CalculateStrategyDecision(cards []string, upCard string) string {
 				result = "HIT"
 			}
 		case 13, 14:
-			if dealerUpCardValue > 4 && dealerUpCardValue < 7 {
+			if (dealerUpCardValue > 4 || (rules.Decks == 1 && dealerUpCardValue == 4)) && dealerUpCardValue < 7 {
 				if len(cards) == 2 {
 					result = "DOUBLE"
 				} else {
@@ -786,8 +1194,14 @@ func CalculateStrategyDecision(cards []string, upCard string) string {
 	} else {
 		if dealerUpCardValue < 7 {
 			switch handValue {
+			case 8:
+				if rules.Decks == 1 && dealerUpCardValue > 4 && len(cards) == 2 {
+					result = "DOUBLE"
+				} else {
+					result = "HIT"
+				}
 			case 9:
-				if dealerUpCardValue == 2 {
+				if dealerUpCardValue == 2 && rules.Decks > 2 {
 					result = "HIT"
 				} else if len(cards) == 2 {
 					result = "DOUBLE"
@@ -815,7 +1229,7 @@ func CalculateStrategyDecision(cards []string, upCard string) string {
 				}
 			}
 		} else if handValue < 17 {
-			if (handValue == 10 || handValue == 11) && dealerUpCardValue < handValue && len(cards) == 2 {
+			if (handValue == 10 || handValue == 11) && (dealerUpCardValue < handValue || (handValue == 11 && (rules.DealerHitsSoft17 || rules.Decks <= 2))) && len(cards) == 2 {
 				result = "DOUBLE"
 			} else {
 				result = "HIT"
@@ -824,9 +1238,60 @@ func CalculateStrategyDecision(cards []string, upCard string) string {
 			result = "STAND"
 		}
 	}
+
+	// Without a hole card check any extra money put out against a ten or ace is lost to a dealer blackjack
+	if !rules.DealerPeeks && dealerUpCardValue >= 10 {
+		switch {
+		case result == "DOUBLE":
+			result = "HIT"
+		case result == "SPLIT" && (cards[0][0] == '8' || dealerUpCardValue == 11):
+			result = "HIT"
+		}
+	}
 	return result
 }
 
+func CalculateCompositionStrategyDecision(cards []string, upCard string, rules BlackjackRules) (string, string) {
+	handValue := CalculateBlackjackValueForCards(cards)
+	dealerUpCardValue := CalculateBlackjackValueForCard(upCard)
+	isSoft := IsSoftTotal(cards)
+	totalDecision := CalculateStrategyDecisionForHand(cards, upCard, rules, isSoft)
+
+	isTwoCardHand := len(cards) == 2
+	isPair := isTwoCardHand && cards[0][0] == cards[1][0]
+	hasCardValues := func(first int, second int) bool {
+		if !isTwoCardHand {
+			return false
+		}
+		firstValue := CalculateBlackjackValueForCard(cards[0])
+		secondValue := CalculateBlackjackValueForCard(cards[1])
+		return (firstValue == first && secondValue == second) || (firstValue == second && secondValue == first)
+	}
+
+	compositionDecision := ""
+	switch {
+	// Soft hands, surrenders and splits always follow the total-dependent chart
+	case isSoft || totalDecision == "SURRENDER" || (isPair && totalDecision == "SPLIT"):
+	// Multi-card 16 loses less by standing against a ten because the small cards it holds help the dealer
+	case handValue == 16 && dealerUpCardValue == 10 && len(cards) >= 3:
+		compositionDecision = "STAND"
+	// A ten in the hand makes hitting 12 safer against a 4, and against 5 and 6 as well in a single deck
+	case hasCardValues(10, 2) && (dealerUpCardValue == 4 || (rules.Decks == 1 && (dealerUpCardValue == 5 || dealerUpCardValue == 6))):
+		compositionDecision = "HIT"
+	// A single-deck 8 made of 6-2 removes the cards that make doubling pay
+	case rules.Decks == 1 && hasCardValues(6, 2) && (dealerUpCardValue == 5 || dealerUpCardValue == 6):
+		compositionDecision = "HIT"
+	// A single-deck 7-7 against a ten removes two of the cards the hand needs
+	case rules.Decks == 1 && isPair && cards[0][0] == '7' && dealerUpCardValue == 10:
+		compositionDecision = "STAND"
+	}
+
+	if compositionDecision != "" && compositionDecision != totalDecision {
+		return compositionDecision, StrategyBasisComposition
+	}
+	return totalDecision, StrategyBasisTotal
+}
+
 func GetBlackjackStrategyHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -839,26 +1304,70 @@ func GetBlackjackStrategyHandler(w http.ResponseWriter, r *http.Request) {
 	cards := strategyBody.Cards
 	upCard := strategyBody.UpCard
 
-	result := CalculateStrategyDecision(cards, upCard)
+	rules := LegacyStrategyRules()
+	if strategyBody.Rules != nil {
+		rules = *strategyBody.Rules
+		rulesErr := ValidateBlackjackRules(rules)
+		if rulesErr != nil {
+			http.Error(w, rulesErr.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var result string
+	switch {
+	case strategyBody.TrueCount != nil:
+		result, _ = CalculateCountingStrategyDecision(cards, upCard, rules, *strategyBody.TrueCount)
+	case strategyBody.Rules == nil:
+		result = CalculateStrategyDecision(cards, upCard)
+	default:
+		result = CalculateStrategyDecisionForRules(cards, upCard, rules)
+	}
 
 	json.NewEncoder(w).Encode(result)
 }
 
-func SetCardsInShoeHandler(w http.ResponseWriter, r *http.Request) {
+func GetBlackjackCompositionStrategyHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var cardsBody CardsBody
+	var strategyBody StrategyBody
+	err := json.NewDecoder(r.Body).Decode(&strategyBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
 
-	vars := mux.Vars(r)
-	shoeSizeStr := vars["shoeSize"]
-	// fmt.Println("SetCardsInShoeHandler called with shoeSize = " + shoeSizeStr)
+	rules := LegacyStrategyRules()
+	if strategyBody.Rules != nil {
+		rules = *strategyBody.Rules
+	}
+	if strategyBody.ShoeSize != 0 {
+		rules.Decks = strategyBody.ShoeSize
+	}
+	rulesErr := ValidateBlackjackRules(rules)
+	if rulesErr != nil {
+		http.Error(w, rulesErr.Error(), http.StatusBadRequest)
+		return
+	}
 
-	shoeSize, shoeSizeErr := strconv.Atoi(shoeSizeStr)
-	if shoeSizeErr != nil {
-		http.Error(w, "Invalid shoe size", http.StatusBadRequest)
+	if len(strategyBody.Cards) < 2 {
+		http.Error(w, "At least two cards are required", http.StatusBadRequest)
 		return
 	}
 
+	decision, basis := CalculateCompositionStrategyDecision(strategyBody.Cards, strategyBody.UpCard, rules)
+
+	json.NewEncoder(w).Encode(StrategyResponseBody{Decision: decision, Basis: basis, Decks: rules.Decks})
+}
+
+func SetCardsInShoeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var cardsBody CardsBody
+
+	vars := mux.Vars(r)
+	shoeID := vars["id"]
+
 	err := json.NewDecoder(r.Body).Decode(&cardsBody)
 	if err != nil {
 		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
@@ -866,7 +1375,13 @@ func SetCardsInShoeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	cards := cardsBody.Cards
 
-	SetCardsInShoe(shoeSize, cards)
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if !SetCardsInShoe(shoeID, cards) {
+		http.NotFound(w, r)
+		return
+	}
 	json.NewEncoder(w).Encode(true)
 }
 
@@ -874,15 +1389,165 @@ func ResetShoeHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	vars := mux.Vars(r)
-	shoeSizeStr := vars["shoeSize"]
+	shoeID := vars["id"]
 
-	shoeSize, shoeSizeErr := strconv.Atoi(shoeSizeStr)
-	if shoeSizeErr != nil {
-		http.Error(w, "Invalid shoe size", http.StatusBadRequest)
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if !ResetShoe(shoeID) {
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(true)
+}
+
+func EndRoundHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	shoeID := vars["id"]
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	shoe, found := FindShoe(shoeID)
+	if !found {
+		http.NotFound(w, r)
 		return
 	}
 
-	ResetShoe(shoeSize)
+	shuffled := shoe.EndRound()
+	json.NewEncoder(w).Encode(shuffled)
+}
+
+func CreateShoeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var shoeBody ShoeBody
+	err := json.NewDecoder(r.Body).Decode(&shoeBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	validationErr := ValidateShoeBody(&shoeBody)
+	if validationErr != nil {
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	shoe := CreateShoe(shoeBody)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(MakeShoeResponseBody(shoe))
+}
+
+func GetShoeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	shoeID := vars["id"]
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	shoe, found := FindShoe(shoeID)
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	json.NewEncoder(w).Encode(MakeShoeResponseBody(shoe))
+}
+
+func GetShufflesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	shoeID := vars["id"]
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	shoe, found := FindShoe(shoeID)
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	json.NewEncoder(w).Encode(MakeShuffleRecordsResponseBody(shoe))
+}
+
+func VerifyShuffleHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var record ShuffleRecord
+	err := json.NewDecoder(r.Body).Decode(&record)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+	if record.Decks < 1 || record.Decks > MaxDecksPerShoe {
+		http.Error(w, fmt.Sprintf("decks must be between 1 and %d", MaxDecksPerShoe), http.StatusBadRequest)
+		return
+	}
+
+	cards, replayErr := ReplayShuffle(record)
+	if replayErr != nil {
+		json.NewEncoder(w).Encode(ShuffleVerificationResponseBody{Verified: false, Reason: replayErr.Error()})
+		return
+	}
+
+	var cardStrings []string
+	for _, card := range cards {
+		cardStrings = append(cardStrings, card.String())
+	}
+	json.NewEncoder(w).Encode(ShuffleVerificationResponseBody{Verified: true, Cards: cardStrings})
+}
+
+func SetClientSeedHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var clientSeedBody ClientSeedBody
+	err := json.NewDecoder(r.Body).Decode(&clientSeedBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	shoeID := vars["id"]
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	shoe, found := FindShoe(shoeID)
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	if !shoe.ProvablyFair {
+		http.Error(w, "Shoe is not provably fair", http.StatusBadRequest)
+		return
+	}
+
+	// A new client seed applies to the pending shuffle, or to the next one if cards have already been drawn
+	shoe.ClientSeed = clientSeedBody.ClientSeed
+	json.NewEncoder(w).Encode(MakeShoeResponseBody(shoe))
+}
+
+func DeleteShoeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	shoeID := vars["id"]
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if !DeleteShoe(shoeID) {
+		http.NotFound(w, r)
+		return
+	}
 	json.NewEncoder(w).Encode(true)
 }
 
@@ -958,11 +1623,13 @@ func GetMaxRankHandler(w http.ResponseWriter, r *http.Request) {
 
 func RollDiceHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	if len(SequencedRolls) == 0 {
-		rand.Seed(time.Now().UnixNano())
 
-		die1 := rand.Intn(6) + 1
-		die2 := rand.Intn(6) + 1
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(SequencedRolls) == 0 {
+		die1 := DiceSource.Intn(6) + 1
+		die2 := DiceSource.Intn(6) + 1
 
 		result := fmt.Sprintf("%d%d", die1, die2)
 
@@ -992,10 +1659,35 @@ func SetDiceRollsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	mutex.Lock()
+	defer mutex.Unlock()
+
 	SequencedRolls = []int{firstRoll, secondRoll}
 	json.NewEncoder(w).Encode(true)
 }
 
+func SetDiceRNGHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var rngBody RNGBody
+	err := json.NewDecoder(r.Body).Decode(&rngBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	validationErr := ValidateRNGBody(&rngBody)
+	if validationErr != nil {
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	DiceSource = NewRandomSource(rngBody)
+	json.NewEncoder(w).Encode(MakeRNGResponseBody(DiceSource))
+}
+
 func init() {
 	SetUpRanksAndSuits()
 }
@@ -1035,16 +1727,10 @@ func SetUpRanksAndSuits() {
 func main() {
 	SetUpRanksAndSuits()
 
-	SizeToShoeMap[1] = NewShoe(1)
-	SizeToShoeMap[2] = NewShoe(2)
-	SizeToShoeMap[3] = NewShoe(3)
-	SizeToShoeMap[4] = NewShoe(4)
-	SizeToShoeMap[5] = NewShoe(5)
-	SizeToShoeMap[6] = NewShoe(6)
-	SizeToShoeMap[7] = NewShoe(7)
-	SizeToShoeMap[8] = NewShoe(8)
-	SizeToShoeMap[9] = NewShoe(9)
-	SizeToShoeMap[10] = NewShoe(10)
+	// Legacy shoes are addressed by their deck count
+	for shoeSize := 1; shoeSize <= MaxDecksPerShoe; shoeSize++ {
+		RegisterShoe(strconv.Itoa(shoeSize), NewShoe(shoeSize))
+	}
 
 	router := mux.NewRouter()
 	// router.Use(corsMiddleware)
@@ -1054,25 +1740,56 @@ func main() {
 	router.HandleFunc("/ranks/{rank1}/{rank2}", GetRankComparisonHandler).Methods("GET")
 	router.HandleFunc("/ranks/max", GetMaxRankHandler).Methods("POST")
 	router.HandleFunc("/cards/resourceName", CardResourceNameHandler).Methods("GET")
-	router.HandleFunc("/shoes/{shoeSize}/setCards", SetCardsInShoeHandler).Methods("POST")
-	router.HandleFunc("/shoes/{shoeSize}/reset", ResetShoeHandler).Methods("POST")
-	router.HandleFunc("/shoes/{shoeSize}/draw", DrawCardHandler).Methods("GET")
-	router.HandleFunc("/shoes/{shoeSize}/cardsLeft", CardsLeftHandler).Methods("GET")
+	router.HandleFunc("/shoes", CreateShoeHandler).Methods("POST")
+	router.HandleFunc("/shoes/{id}", GetShoeHandler).Methods("GET")
+	router.HandleFunc("/shoes/{id}", DeleteShoeHandler).Methods("DELETE")
+	router.HandleFunc("/shoes/{id}/setCards", SetCardsInShoeHandler).Methods("POST")
+	router.HandleFunc("/shoes/{id}/reset", ResetShoeHandler).Methods("POST")
+	router.HandleFunc("/shoes/{id}/draw", DrawCardHandler).Methods("GET")
+	router.HandleFunc("/shoes/{id}/cardsLeft", CardsLeftHandler).Methods("GET")
+	router.HandleFunc("/shoes/{id}/endRound", EndRoundHandler).Methods("POST")
+	router.HandleFunc("/shoes/{id}/shuffles", GetShufflesHandler).Methods("GET")
+	router.HandleFunc("/shoes/{id}/clientSeed", SetClientSeedHandler).Methods("POST")
+	router.HandleFunc("/shuffles/verify", VerifyShuffleHandler).Methods("POST")
+	router.HandleFunc("/shoes/{id}/count", GetShoeCountHandler).Methods("GET")
 	router.HandleFunc("/blackjack", GetBlackjackForDealerHandler).Methods("POST")
 	router.HandleFunc("/blackjack/strategy", GetBlackjackStrategyHandler).Methods("POST")
+	router.HandleFunc("/blackjack/strategy/composition", GetBlackjackCompositionStrategyHandler).Methods("POST")
+	router.HandleFunc("/blackjack/strategy/insurance", GetInsuranceStrategyHandler).Methods("POST")
+	router.HandleFunc("/blackjack/ev", GetBlackjackEVHandler).Methods("POST")
+	router.HandleFunc("/blackjack/sidebets", GetBlackjackSideBetsHandler).Methods("POST")
 	router.HandleFunc("/blackjack/soft", GetBlackjackSoftHandler).Methods("POST")
 	router.HandleFunc("/blackjack/bust", GetBlackjackBustHandler).Methods("POST")
 	router.HandleFunc("/blackjack/ranks/{label}", GetRankBlackjackValueHandler).Methods("GET")
 	router.HandleFunc("/blackjack/values", GetBlackjackValueForCardsHandler).Methods("POST")
 	router.HandleFunc("/blackjack/values/ranks", GetBlackjackRanksForValuesHandler).Methods("POST")
 	router.HandleFunc("/blackjack/values/description", GetBlackjackDescriptionHandler).Methods("POST")
+	router.HandleFunc("/blackjack/rounds", CreateBlackjackRoundHandler).Methods("POST")
+	router.HandleFunc("/blackjack/rounds/{id}", GetBlackjackRoundHandler).Methods("GET")
+	router.HandleFunc("/blackjack/rounds/{id}/actions", BlackjackRoundActionHandler).Methods("POST")
+	router.HandleFunc("/baccarat/coups", CreateBaccaratCoupHandler).Methods("POST")
+	router.HandleFunc("/baccarat/sidebets", GetBaccaratSideBetsHandler).Methods("POST")
+	router.HandleFunc("/baccarat/shoes/{id}/roads", GetBaccaratRoadsHandler).Methods("GET")
+	router.HandleFunc("/baccarat/probabilities", GetBaccaratProbabilitiesHandler).Methods("POST")
 	router.HandleFunc("/baccarat/natural", GetBaccaratNaturalHandler).Methods("POST")
 	router.HandleFunc("/baccarat/value", GetBaccaratValueForCardsHandler).Methods("POST")
 	router.HandleFunc("/baccarat/ranks/{label}", GetRankBaccaratValueHandler).Methods("GET")
 	router.HandleFunc("/poker/strength", GetPokerHandStrengthHandler).Methods("POST")
 	router.HandleFunc("/poker/flush/ranks", GetPokerFlushRanksHandler).Methods("POST")
 	router.HandleFunc("/poker/flush", GetPokerFlushHandler).Methods("POST")
+	router.HandleFunc("/poker/evaluate", GetPokerEvaluationHandler).Methods("POST")
+	router.HandleFunc("/poker/showdown", GetPokerShowdownHandler).Methods("POST")
+	router.HandleFunc("/poker/pots", GetPokerPotsHandler).Methods("POST")
+	router.HandleFunc("/poker/equity", GetPokerEquityHandler).Methods("POST")
+	router.HandleFunc("/poker/tables", CreatePokerTableHandler).Methods("POST")
+	router.HandleFunc("/poker/tables/{id}", GetPokerTableHandler).Methods("GET")
+	router.HandleFunc("/poker/tables/{id}/seats", SeatPokerPlayerHandler).Methods("POST")
+	router.HandleFunc("/poker/tables/{id}/hands", StartPokerHandHandler).Methods("POST")
+	router.HandleFunc("/poker/tables/{id}/actions", PokerTableActionHandler).Methods("POST")
+	router.HandleFunc("/simulations/blackjack", CreateBlackjackSimulationHandler).Methods("POST")
+	router.HandleFunc("/simulations/{id}", GetSimulationHandler).Methods("GET")
 	router.HandleFunc("/dice/roll", RollDiceHandler).Methods("GET")
+	router.HandleFunc("/dice/rng", SetDiceRNGHandler).Methods("POST")
 	router.HandleFunc("/dice/{roll1}/{roll2}", SetDiceRollsHandler).Methods("POST")
 
 	port := 5001
@@ -1082,6 +1799,7 @@ func main() {
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://192.168.1.203:3000"},
 		AllowCredentials: true,
+		ExposedHeaders:   []string{NeedsShuffleHeader},
 	})
 
 	handler := c.Handler(router)
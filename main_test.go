@@ -1,7 +1,13 @@
 package main
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/gorilla/mux"
 )
 
 func TestBlackjack(t *testing.T) {
@@ -597,6 +603,66 @@ func TestBlackjackDecisions(t *testing.T) {
 
 }
 
+func TestCompositionStrategy(t *testing.T) {
+	sixDecks := LegacyStrategyRules()
+	singleDeck := LegacyStrategyRules()
+	singleDeck.Decks = 1
+
+	decision, basis := CalculateCompositionStrategyDecision([]string{"T", "6"}, "TS", sixDecks)
+	if decision != "HIT" || basis != StrategyBasisTotal {
+		t.Errorf("two-card 16 vs 10 expected to hit on the total")
+	}
+
+	decision, basis = CalculateCompositionStrategyDecision([]string{"4", "4", "8"}, "TS", sixDecks)
+	if decision != "STAND" || basis != StrategyBasisComposition {
+		t.Errorf("multi-card 16 vs 10 expected to stand on composition")
+	}
+
+	decision, basis = CalculateCompositionStrategyDecision([]string{"6", "5", "A", "4"}, "TS", sixDecks)
+	if decision != "STAND" || basis != StrategyBasisComposition {
+		t.Errorf("multi-card hard 16 with an ace vs 10 expected to stand on composition")
+	}
+
+	decision, basis = CalculateCompositionStrategyDecision([]string{"T", "2"}, "4S", sixDecks)
+	if decision != "HIT" || basis != StrategyBasisComposition {
+		t.Errorf("10-2 vs 4 expected to hit on composition")
+	}
+
+	decision, _ = CalculateCompositionStrategyDecision([]string{"9", "3"}, "4S", sixDecks)
+	if decision != "STAND" {
+		t.Errorf("9-3 vs 4 expected to stand")
+	}
+
+	decision, basis = CalculateCompositionStrategyDecision([]string{"5", "3"}, "6S", singleDeck)
+	if decision != "DOUBLE" || basis != StrategyBasisTotal {
+		t.Errorf("single deck 8 vs 6 expected to double")
+	}
+
+	decision, basis = CalculateCompositionStrategyDecision([]string{"6", "2"}, "6S", singleDeck)
+	if decision != "HIT" || basis != StrategyBasisComposition {
+		t.Errorf("single deck 6-2 vs 6 expected to hit on composition")
+	}
+
+	if CalculateStrategyDecisionForRules([]string{"6", "5"}, "AS", singleDeck) != "DOUBLE" {
+		t.Errorf("single deck 11 vs A expected to double")
+	}
+	if CalculateStrategyDecisionForRules([]string{"5", "4"}, "2S", singleDeck) != "DOUBLE" {
+		t.Errorf("single deck 9 vs 2 expected to double")
+	}
+
+	decision, basis = CalculateCompositionStrategyDecision([]string{"T"}, "4", sixDecks)
+	if basis != StrategyBasisTotal {
+		t.Errorf("one-card hand expected to fall back to the total, got %s", decision)
+	}
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("POST", "/blackjack/strategy/composition", strings.NewReader(`{"cards":["T"],"upCard":"4"}`))
+	GetBlackjackCompositionStrategyHandler(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("one-card hand expected to be rejected, got %d", recorder.Code)
+	}
+}
+
 func TestBaccaratNaturals(t *testing.T) {
 
 	naturalHands := [][]string{
@@ -761,6 +827,247 @@ func TestBaccaratHandCreation(t *testing.T) {
 
 }
 
+func TestShoeRegistry(t *testing.T) {
+	first := CreateShoe(ShoeBody{Decks: 6, Penetration: 0.8})
+	second := CreateShoe(ShoeBody{Decks: 6, Penetration: DefaultPenetration})
+
+	if first.ID == second.ID {
+		t.Errorf("unique shoe ids expected")
+	}
+
+	first.DrawCard()
+	if first.CardsLeft() != 6*52-1 {
+		t.Errorf("draw expected to remove a card from the first shoe")
+	}
+	if second.CardsLeft() != 6*52 {
+		t.Errorf("second shoe expected to be untouched")
+	}
+
+	if !ResetShoe(first.ID) || first.CardsLeft() != 6*52 {
+		t.Errorf("reset expected to refill the shoe")
+	}
+
+	if !DeleteShoe(first.ID) {
+		t.Errorf("delete expected to succeed")
+	}
+	if _, found := FindShoe(first.ID); found {
+		t.Errorf("deleted shoe not expected")
+	}
+	DeleteShoe(second.ID)
+
+	shoeBody := ShoeBody{Decks: 11}
+	if ValidateShoeBody(&shoeBody) == nil {
+		t.Errorf("too many decks expected to be rejected")
+	}
+	shoeBody = ShoeBody{Decks: 2}
+	if ValidateShoeBody(&shoeBody) != nil || shoeBody.Penetration != DefaultPenetration {
+		t.Errorf("default penetration expected")
+	}
+}
+
+func TestShoeCutCard(t *testing.T) {
+	shoe := NewShoe(1)
+	shoe.Penetration = 0.5
+
+	for i := 0; i < 25; i++ {
+		shoe.DrawCard()
+	}
+	if shoe.NeedsShuffle() {
+		t.Errorf("cut card not expected before 26 cards are dealt")
+	}
+	if shoe.EndRound() {
+		t.Errorf("shuffle not expected before the cut card")
+	}
+
+	shoe.ID = "cut-card"
+	Shoes[shoe.ID] = shoe
+	defer DeleteShoe(shoe.ID)
+	recorder := httptest.NewRecorder()
+	DrawCardHandler(recorder, mux.SetURLVars(httptest.NewRequest("GET", "/shoes/cut-card/draw", nil), map[string]string{"id": shoe.ID}))
+	var card string
+	if json.NewDecoder(recorder.Body).Decode(&card) != nil || len(card) != 2 {
+		t.Errorf("draw expected to return a bare card string")
+	}
+	if !shoe.NeedsShuffle() || recorder.Header().Get(NeedsShuffleHeader) != "true" {
+		t.Errorf("cut card expected after 26 cards are dealt")
+	}
+
+	shoe.DrawCard()
+	if shoe.CardsLeft() != 25 {
+		t.Errorf("shoe expected to keep dealing past the cut card")
+	}
+
+	if !shoe.EndRound() || shoe.CardsLeft() != 52 {
+		t.Errorf("shuffle expected at the end of the round")
+	}
+
+	for i := 0; i < 53; i++ {
+		shoe.DrawCard()
+	}
+	if shoe.CardsLeft() != 51 {
+		t.Errorf("exhausted shoe expected to be reshuffled")
+	}
+}
+
+func TestShoeSequence(t *testing.T) {
+	shoe := NewShoe(2)
+
+	cardCounts := make(map[string]int)
+	for _, card := range shoe.RemainingCards() {
+		cardCounts[card.String()]++
+	}
+	if len(cardCounts) != 52 {
+		t.Errorf("52 distinct cards expected")
+	}
+	for card, count := range cardCounts {
+		if count != 2 {
+			t.Errorf("2 copies of %s expected", card)
+		}
+	}
+
+	remaining := shoe.RemainingCards()
+	for i := 0; i < 10; i++ {
+		drawnCard := shoe.DrawCard()
+		if drawnCard != remaining[i] {
+			t.Errorf("draws expected to come from the top of the shoe")
+		}
+	}
+	if shoe.CardsLeft() != 2*52-10 {
+		t.Errorf("94 cards left expected")
+	}
+}
+
+func TestSeededShoes(t *testing.T) {
+	first := NewShoeWithSource(6, NewSeededSource(42))
+	second := NewShoeWithSource(6, NewSeededSource(42))
+
+	if first.String() != second.String() {
+		t.Errorf("equal seeds expected to produce equal shuffles")
+	}
+
+	first.Reset()
+	reshuffleSeed := first.Source.(*SeededSource).Seed
+	if reshuffleSeed == 42 {
+		t.Errorf("new seed expected on reshuffle")
+	}
+	replay := NewShoeWithSource(6, NewSeededSource(reshuffleSeed))
+	if first.String() != replay.String() {
+		t.Errorf("reshuffle expected to be reproducible from its seed")
+	}
+
+	rngBody := RNGBody{Mode: RNGModeSeeded}
+	if ValidateRNGBody(&rngBody) != nil || rngBody.Seed == nil {
+		t.Errorf("seed expected to be generated for seeded mode")
+	}
+	seed := int64(7)
+	rngBody = RNGBody{Mode: RNGModeCrypto, Seed: &seed}
+	if ValidateRNGBody(&rngBody) == nil {
+		t.Errorf("seed not expected for crypto mode")
+	}
+}
+
+func TestShuffleAuditLog(t *testing.T) {
+	seededShoe := RegisterShoe("audit-seeded", NewShoeWithSource(2, NewSeededSource(99)))
+	cryptoShoe := RegisterShoe("audit-crypto", NewShoe(2))
+	defer DeleteShoe(seededShoe.ID)
+	defer DeleteShoe(cryptoShoe.ID)
+
+	for _, shoe := range []*Shoe{seededShoe, cryptoShoe} {
+		expectedOrder := shoe.String()
+		shoe.Reset()
+
+		if len(shoe.Shuffles) != 2 {
+			t.Errorf("2 shuffle records expected")
+		}
+		if shoe.Shuffles[0].ShoeID != shoe.ID {
+			t.Errorf("shuffle record expected to carry the shoe id")
+		}
+
+		replayedCards, err := ReplayShuffle(shoe.Shuffles[0])
+		if err != nil {
+			t.Errorf("unexpected replay error: %v", err)
+		}
+		replayedShoe := &Shoe{Cards: replayedCards}
+		if replayedShoe.String() != expectedOrder {
+			t.Errorf("replayed order expected to match the original shuffle")
+		}
+	}
+
+	records := MakeShuffleRecordsResponseBody(cryptoShoe)
+	if records[0].Seed == "" || records[1].Seed != "" {
+		t.Errorf("only the current crypto shuffle seed expected to be hidden")
+	}
+
+	tampered := seededShoe.Shuffles[1]
+	tampered.Seed = "100"
+	if _, err := ReplayShuffle(tampered); err == nil {
+		t.Errorf("tampered seed expected to fail verification")
+	}
+}
+
+func TestProvablyFairShoe(t *testing.T) {
+	shoe := CreateShoe(ShoeBody{Decks: 1, Penetration: DefaultPenetration, RNG: RNGModeCrypto, ProvablyFair: true, ClientSeed: "first"})
+	defer DeleteShoe(shoe.ID)
+
+	commitment := MakeShoeResponseBody(shoe).Commitment
+	if commitment == "" || !shoe.PendingShuffle {
+		t.Errorf("commitment expected before the first draw")
+	}
+
+	shoe.ClientSeed = "player chosen"
+	firstCard := shoe.DrawCard()
+	if shoe.PendingShuffle {
+		t.Errorf("shuffle expected to be applied at the first draw")
+	}
+
+	if MakeShuffleRecordsResponseBody(shoe)[0].Seed != "" {
+		t.Errorf("server seed not expected before reset")
+	}
+
+	ResetShoe(shoe.ID)
+	revealed := MakeShuffleRecordsResponseBody(shoe)[0]
+	if revealed.Seed == "" || revealed.Commitment != commitment || revealed.ClientSeed != "player chosen" {
+		t.Errorf("server seed expected to be revealed on reset")
+	}
+
+	replayedCards, err := ReplayShuffle(revealed)
+	if err != nil {
+		t.Errorf("unexpected replay error: %v", err)
+	} else if replayedCards[0] != firstCard {
+		t.Errorf("replayed order expected to start with the first drawn card")
+	}
+
+	recordJSON, _ := json.Marshal(revealed)
+	recorder := httptest.NewRecorder()
+	VerifyShuffleHandler(recorder, httptest.NewRequest("POST", "/shuffles/verify", strings.NewReader(string(recordJSON))))
+	var verification ShuffleVerificationResponseBody
+	json.NewDecoder(recorder.Body).Decode(&verification)
+	if !verification.Verified || len(verification.Cards) != 52 || verification.Cards[0] != firstCard.String() {
+		t.Errorf("verify route expected to replay the revealed shuffle")
+	}
+
+	revealed.ClientSeed = "someone else"
+	if _, err := ReplayShuffle(revealed); err == nil {
+		t.Errorf("different client seed expected to fail verification")
+	}
+
+	unused := CreateShoe(ShoeBody{Decks: 1, Penetration: DefaultPenetration, RNG: RNGModeCrypto, ProvablyFair: true})
+	defer DeleteShoe(unused.ID)
+	ResetShoe(unused.ID)
+	voided := MakeShuffleRecordsResponseBody(unused)
+	if len(voided) != 2 || !voided[0].Voided || voided[1].Voided || !unused.PendingShuffle {
+		t.Errorf("commitment reset before the first draw expected to be voided")
+	}
+	if _, err := ReplayShuffle(voided[0]); err == nil {
+		t.Errorf("voided shuffle not expected to verify")
+	}
+
+	shoeBody := ShoeBody{Decks: 1, RNG: RNGModeSeeded, ProvablyFair: true}
+	if ValidateShoeBody(&shoeBody) == nil {
+		t.Errorf("seeded provably fair shoe not expected")
+	}
+}
+
 // func TestPoker(t *testing.T) {
 // 	cards := []string{"2S", "3S", "4S", "5S", "6S"}
 
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// Structs
+
+type PokerCard struct {
+	Rank  int
+	Suit  byte
+	Label string
+}
+
+type PokerHand struct {
+	Cards    []string `json:"cards"`
+	Name     string   `json:"name"`
+	Strength uint8    `json:"strength"`
+	Value    uint32   `json:"value"`
+}
+
+type ShowdownPlayerBody struct {
+	ID    string   `json:"id"`
+	Cards []string `json:"cards"`
+}
+
+type ShowdownBody struct {
+	Board   []string             `json:"board"`
+	Players []ShowdownPlayerBody `json:"players"`
+}
+
+type ShowdownPlayerResult struct {
+	ID   string    `json:"id"`
+	Hand PokerHand `json:"hand"`
+}
+
+type ShowdownResult struct {
+	Winners  []string               `json:"winners"`
+	SplitPot bool                   `json:"splitPot"`
+	Players  []ShowdownPlayerResult `json:"players"`
+}
+
+// Functions
+
+func PokerRankValue(rank byte) int {
+	if rank == 'A' {
+		return 14
+	}
+	return FindOrderForRank(string(rank))
+}
+
+func IsValidCard(card string) bool {
+	if len(card) != 2 {
+		return false
+	}
+
+	validRank := false
+	for _, item := range ranks {
+		if item.Label[0] == card[0] {
+			validRank = true
+		}
+	}
+
+	validSuit := false
+	for _, item := range suits {
+		if item.Label[0] == card[1] {
+			validSuit = true
+		}
+	}
+
+	return validRank && validSuit
+}
+
+func NewPokerCard(card string) PokerCard {
+	return PokerCard{Rank: PokerRankValue(card[0]), Suit: card[1], Label: card}
+}
+
+func ParsePokerCards(cardStrings []string) ([]PokerCard, error) {
+	var cards []PokerCard
+	seen := make(map[string]bool)
+
+	for _, cardString := range cardStrings {
+		if !IsValidCard(cardString) {
+			return nil, fmt.Errorf("invalid card %q", cardString)
+		}
+		if seen[cardString] {
+			return nil, fmt.Errorf("duplicate card %q", cardString)
+		}
+		seen[cardString] = true
+		cards = append(cards, NewPokerCard(cardString))
+	}
+
+	return cards, nil
+}
+
+func FindPokerHandType(name string) PokerHandType {
+	for _, item := range pokerHandTypes {
+		if item.Name == name {
+			return item
+		}
+	}
+	return PokerHandType{Name: name}
+}
+
+func SortPokerCards(cards []PokerCard) []PokerCard {
+	sortedCards := make([]PokerCard, len(cards))
+	copy(sortedCards, cards)
+	sort.SliceStable(sortedCards, func(i, j int) bool {
+		return sortedCards[i].Rank > sortedCards[j].Rank
+	})
+	return sortedCards
+}
+
+func FindFlush(sortedCards []PokerCard, size int) []PokerCard {
+	suitGroups := make(map[byte][]PokerCard)
+	var flushCards []PokerCard
+	for _, card := range sortedCards {
+		suitGroups[card.Suit] = append(suitGroups[card.Suit], card)
+		if len(suitGroups[card.Suit]) >= size {
+			flushCards = suitGroups[card.Suit]
+		}
+	}
+	return flushCards
+}
+
+func FindStraight(sortedCards []PokerCard, size int) []PokerCard {
+	var distinct []PokerCard
+	for _, card := range sortedCards {
+		if len(distinct) == 0 || distinct[len(distinct)-1].Rank != card.Rank {
+			distinct = append(distinct, card)
+		}
+	}
+
+	for i := 0; i+size-1 < len(distinct); i++ {
+		if distinct[i].Rank-distinct[i+size-1].Rank == size-1 {
+			return distinct[i : i+size]
+		}
+	}
+
+	// The ace also plays low, as in the wheel 5-4-3-2-A
+	if len(distinct) >= size && distinct[0].Rank == 14 {
+		lowCards := distinct[len(distinct)-(size-1):]
+		if lowCards[0].Rank == size && lowCards[size-2].Rank == 2 {
+			return append(append([]PokerCard{}, lowCards...), distinct[0])
+		}
+	}
+
+	return nil
+}
+
+func takeKickers(hand []PokerCard, sortedCards []PokerCard, size int) []PokerCard {
+	usedRanks := make(map[int]bool)
+	for _, card := range hand {
+		usedRanks[card.Rank] = true
+	}
+
+	for _, card := range sortedCards {
+		if len(hand) == size {
+			break
+		}
+		if !usedRanks[card.Rank] {
+			hand = append(hand, card)
+		}
+	}
+
+	return hand
+}
+
+func FindBestPokerHand(cards []PokerCard) ([]PokerCard, string) {
+	sortedCards := SortPokerCards(cards)
+	flushCards := FindFlush(sortedCards, 5)
+
+	if flushCards != nil {
+		if straight := FindStraight(flushCards, 5); straight != nil {
+			if straight[0].Rank == 14 {
+				return straight, "Royal Flush"
+			}
+			return straight, "Straight Flush"
+		}
+	}
+
+	var rankGroups [][]PokerCard
+	for _, card := range sortedCards {
+		if len(rankGroups) > 0 && rankGroups[len(rankGroups)-1][0].Rank == card.Rank {
+			rankGroups[len(rankGroups)-1] = append(rankGroups[len(rankGroups)-1], card)
+		} else {
+			rankGroups = append(rankGroups, []PokerCard{card})
+		}
+	}
+	sort.SliceStable(rankGroups, func(i, j int) bool {
+		return len(rankGroups[i]) > len(rankGroups[j])
+	})
+
+	bestSize := 5
+	if len(sortedCards) < bestSize {
+		bestSize = len(sortedCards)
+	}
+
+	first := rankGroups[0]
+	var second []PokerCard
+	if len(rankGroups) > 1 {
+		second = rankGroups[1]
+	}
+
+	if len(first) == 4 {
+		return takeKickers(append([]PokerCard{}, first...), sortedCards, bestSize), "Four Of A Kind"
+	}
+	if len(first) == 3 && len(second) >= 2 {
+		return append(append([]PokerCard{}, first...), second[:2]...), "Full House"
+	}
+	if flushCards != nil {
+		return flushCards[:5], "Flush"
+	}
+	if straight := FindStraight(sortedCards, 5); straight != nil {
+		return straight, "Straight"
+	}
+	if len(first) == 3 {
+		return takeKickers(append([]PokerCard{}, first...), sortedCards, bestSize), "Three Of A Kind"
+	}
+	if len(first) == 2 && len(second) == 2 {
+		return takeKickers(append(append([]PokerCard{}, first...), second...), sortedCards, bestSize), "Two Pair"
+	}
+	if len(first) == 2 {
+		return takeKickers(append([]PokerCard{}, first...), sortedCards, bestSize), "Pair"
+	}
+
+	return sortedCards[:bestSize], "High Card"
+}
+
+func CalculatePokerHandValue(bestCards []PokerCard, handType PokerHandType) uint32 {
+	value := uint32(handType.Strength) << 20
+
+	// Only a straight plays the ace low, quads, full houses and two pair laid out 5-5-...-A keep it high
+	isStraight := handType.Name == "Straight" || handType.Name == "Straight Flush"
+	isWheel := isStraight && len(bestCards) == 5 && bestCards[0].Rank == 5 && bestCards[4].Rank == 14
+	for i, card := range bestCards {
+		rank := card.Rank
+		if isWheel && rank == 14 {
+			rank = 1
+		}
+		value |= uint32(rank) << uint(16-4*i)
+	}
+
+	return value
+}
+
+func EvaluatePokerHand(cards []PokerCard) PokerHand {
+	bestCards, name := FindBestPokerHand(cards)
+	handType := FindPokerHandType(name)
+
+	var labels []string
+	for _, card := range bestCards {
+		labels = append(labels, card.Label)
+	}
+
+	return PokerHand{
+		Cards:    labels,
+		Name:     handType.Name,
+		Strength: handType.Strength,
+		Value:    CalculatePokerHandValue(bestCards, handType),
+	}
+}
+
+func CalculateShowdown(board []string, players []ShowdownPlayerBody) (ShowdownResult, error) {
+	var result ShowdownResult
+
+	if len(players) == 0 {
+		return result, fmt.Errorf("at least one player is required")
+	}
+
+	allCards := append([]string{}, board...)
+	seenIDs := make(map[string]bool)
+	for _, player := range players {
+		if seenIDs[player.ID] {
+			return result, fmt.Errorf("duplicate player id %q", player.ID)
+		}
+		seenIDs[player.ID] = true
+
+		handSize := len(board) + len(player.Cards)
+		if handSize < 5 || handSize > 7 {
+			return result, fmt.Errorf("player %q has %d cards with the board, between 5 and 7 are required", player.ID, handSize)
+		}
+		allCards = append(allCards, player.Cards...)
+	}
+
+	if _, err := ParsePokerCards(allCards); err != nil {
+		return result, err
+	}
+
+	boardCards, _ := ParsePokerCards(board)
+	var bestValue uint32
+	for _, player := range players {
+		holeCards, _ := ParsePokerCards(player.Cards)
+		hand := EvaluatePokerHand(append(append([]PokerCard{}, boardCards...), holeCards...))
+		result.Players = append(result.Players, ShowdownPlayerResult{ID: player.ID, Hand: hand})
+
+		if hand.Value > bestValue {
+			bestValue = hand.Value
+			result.Winners = []string{player.ID}
+		} else if hand.Value == bestValue {
+			result.Winners = append(result.Winners, player.ID)
+		}
+	}
+	result.SplitPot = len(result.Winners) > 1
+
+	return result, nil
+}
+
+// Handlers
+
+func GetPokerEvaluationHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var cardsBody CardsBody
+	err := json.NewDecoder(r.Body).Decode(&cardsBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	if len(cardsBody.Cards) < 5 || len(cardsBody.Cards) > 7 {
+		http.Error(w, "Between 5 and 7 cards are required", http.StatusBadRequest)
+		return
+	}
+
+	cards, parseErr := ParsePokerCards(cardsBody.Cards)
+	if parseErr != nil {
+		http.Error(w, parseErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(EvaluatePokerHand(cards))
+}
+
+func GetPokerShowdownHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var showdownBody ShowdownBody
+	err := json.NewDecoder(r.Body).Decode(&showdownBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	result, showdownErr := CalculateShowdown(showdownBody.Board, showdownBody.Players)
+	if showdownErr != nil {
+		http.Error(w, showdownErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
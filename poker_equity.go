@@ -0,0 +1,451 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// Structs
+
+type EquityPlayerBody struct {
+	ID    string   `json:"id"`
+	Cards []string `json:"cards"`
+	Range string   `json:"range"`
+}
+
+type EquityBody struct {
+	Players    []EquityPlayerBody `json:"players"`
+	Board      []string           `json:"board"`
+	Dead       []string           `json:"dead"`
+	Iterations int                `json:"iterations"`
+	Seed       *int64             `json:"seed"`
+}
+
+type PlayerEquity struct {
+	ID                 string    `json:"id"`
+	Win                float64   `json:"win"`
+	Tie                float64   `json:"tie"`
+	Equity             float64   `json:"equity"`
+	ConfidenceInterval []float64 `json:"confidenceInterval,omitempty"`
+}
+
+type EquityResult struct {
+	Method    string         `json:"method"`
+	Showdowns int            `json:"showdowns"`
+	Seed      *int64         `json:"seed,omitempty"`
+	Players   []PlayerEquity `json:"players"`
+}
+
+type equityCombo [2]PokerCard
+
+type equityTally struct {
+	Wins          []float64
+	Ties          []float64
+	Equity        []float64
+	EquitySquares []float64
+	Showdowns     int
+	cards         []PokerCard
+}
+
+// Package Variables
+
+const EquityMethodExact = "EXACT"
+const EquityMethodMonteCarlo = "MONTE_CARLO"
+
+// Above this many hand evaluations the equity is estimated by sampling instead
+const MaxExactEquityEvaluations = 200000
+
+const DefaultEquityIterations = 20000
+const MaxEquityIterations = 100000
+
+const pokerRankLabels = "23456789TJQKA"
+
+// Functions
+
+func PokerRankLabel(rank int) byte {
+	return pokerRankLabels[rank-2]
+}
+
+func parseRangeHand(hand string) (int, int, byte, error) {
+	if len(hand) < 2 || len(hand) > 3 {
+		return 0, 0, 0, fmt.Errorf("invalid range hand %q", hand)
+	}
+	high, low := PokerRankValue(hand[0]), PokerRankValue(hand[1])
+	if high < 2 || low < 2 || (len(hand) == 3 && hand[2] != 's' && hand[2] != 'o') {
+		return 0, 0, 0, fmt.Errorf("invalid range hand %q", hand)
+	}
+	if high < low {
+		high, low = low, high
+	}
+
+	kind := byte(0)
+	if len(hand) == 3 {
+		kind = hand[2]
+	}
+	if high == low && kind != 0 {
+		return 0, 0, 0, fmt.Errorf("pairs cannot be suited or offsuit in %q", hand)
+	}
+	return high, low, kind, nil
+}
+
+func rangeHandCombos(high int, low int, kind byte) [][]string {
+	var combos [][]string
+	for i, first := range suits {
+		for j, second := range suits {
+			// Each pair of suits is only listed once for a pocket pair
+			if (high == low && j <= i) || (kind == 's' && i != j) || (kind == 'o' && i == j) {
+				continue
+			}
+			combos = append(combos, []string{string(PokerRankLabel(high)) + first.Label, string(PokerRankLabel(low)) + second.Label})
+		}
+	}
+	return combos
+}
+
+func ParsePokerRange(text string) ([][]string, error) {
+	var combos [][]string
+	seen := make(map[string]bool)
+	add := func(combo []string) {
+		key := combo[0] + combo[1]
+		if combo[1] > combo[0] {
+			key = combo[1] + combo[0]
+		}
+		if !seen[key] {
+			seen[key] = true
+			combos = append(combos, combo)
+		}
+	}
+
+	for _, token := range strings.Split(text, ",") {
+		token = strings.TrimSpace(token)
+
+		// Exact hole cards such as AHKH
+		if len(token) == 4 && IsValidCard(token[:2]) && IsValidCard(token[2:]) && token[:2] != token[2:] {
+			add([]string{token[:2], token[2:]})
+			continue
+		}
+
+		plus := strings.HasSuffix(token, "+")
+		ends := strings.Split(strings.TrimSuffix(token, "+"), "-")
+		if len(ends) > 2 || (plus && len(ends) == 2) {
+			return nil, fmt.Errorf("invalid range token %q", token)
+		}
+
+		high, low, kind, err := parseRangeHand(ends[0])
+		if err != nil {
+			return nil, err
+		}
+		fromLow, toLow := low, low
+		switch {
+		case len(ends) == 2:
+			// Ranges such as TT-77 or A5s-A2s run between two hands with the same shape
+			otherHigh, otherLow, otherKind, otherErr := parseRangeHand(ends[1])
+			if otherErr != nil {
+				return nil, otherErr
+			}
+			if otherKind != kind || (high == low) != (otherHigh == otherLow) || (high != low && otherHigh != high) {
+				return nil, fmt.Errorf("invalid range token %q", token)
+			}
+			fromLow, toLow = otherLow, low
+			if fromLow > toLow {
+				fromLow, toLow = toLow, fromLow
+			}
+		case plus && high == low:
+			toLow = 14
+		case plus:
+			toLow = high - 1
+		}
+
+		for kicker := fromLow; kicker <= toLow; kicker++ {
+			handHigh := high
+			if high == low {
+				handHigh = kicker
+			}
+			for _, combo := range rangeHandCombos(handHigh, kicker, kind) {
+				add(combo)
+			}
+		}
+	}
+	return combos, nil
+}
+
+func ValidateEquityBody(equityBody *EquityBody) error {
+	if len(equityBody.Players) < 2 || len(equityBody.Players) > MaxPokerSeats {
+		return fmt.Errorf("between 2 and %d players are required", MaxPokerSeats)
+	}
+	switch len(equityBody.Board) {
+	case 0, 3, 4, 5:
+	default:
+		return fmt.Errorf("the board must have 0, 3, 4 or 5 cards")
+	}
+
+	seenIDs := make(map[string]bool)
+	for _, player := range equityBody.Players {
+		if seenIDs[player.ID] {
+			return fmt.Errorf("duplicate player id %q", player.ID)
+		}
+		seenIDs[player.ID] = true
+		if (len(player.Cards) == 0) == (player.Range == "") {
+			return fmt.Errorf("player %q needs either hole cards or a range", player.ID)
+		}
+		if player.Range == "" && len(player.Cards) != 2 {
+			return fmt.Errorf("player %q must have two hole cards", player.ID)
+		}
+	}
+
+	if equityBody.Iterations == 0 {
+		equityBody.Iterations = DefaultEquityIterations
+	}
+	if equityBody.Iterations < 1 || equityBody.Iterations > MaxEquityIterations {
+		return fmt.Errorf("iterations must be between 1 and %d", MaxEquityIterations)
+	}
+	if equityBody.Seed == nil {
+		seed := NewRandomSeed()
+		equityBody.Seed = &seed
+	}
+	return nil
+}
+
+func newEquityTally(players int) *equityTally {
+	return &equityTally{
+		Wins:          make([]float64, players),
+		Ties:          make([]float64, players),
+		Equity:        make([]float64, players),
+		EquitySquares: make([]float64, players),
+	}
+}
+
+func (tally *equityTally) Record(board []PokerCard, holes []equityCombo) {
+	values := make([]uint32, len(holes))
+	var bestValue uint32
+	winners := 0
+	for i, hole := range holes {
+		tally.cards = append(append(tally.cards[:0], board...), hole[0], hole[1])
+		// Ranked the same way as a showdown so equity and the table never disagree on a winner
+		values[i] = EvaluatePokerHand(tally.cards).Value
+
+		if values[i] > bestValue {
+			bestValue, winners = values[i], 1
+		} else if values[i] == bestValue {
+			winners++
+		}
+	}
+
+	share := 1 / float64(winners)
+	for i, value := range values {
+		if value != bestValue {
+			continue
+		}
+		if winners == 1 {
+			tally.Wins[i]++
+		} else {
+			tally.Ties[i]++
+		}
+		tally.Equity[i] += share
+		tally.EquitySquares[i] += share * share
+	}
+	tally.Showdowns++
+}
+
+func binomial(n int, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	result := 1.0
+	for i := 0; i < k; i++ {
+		result = result * float64(n-i) / float64(i+1)
+	}
+	return result
+}
+
+func enumerateEquity(combos [][]equityCombo, board []PokerCard, deck []PokerCard, tally *equityTally) {
+	holes := make([]equityCombo, len(combos))
+	used := make(map[string]bool)
+
+	var dealBoard func(start int, board []PokerCard)
+	dealBoard = func(start int, board []PokerCard) {
+		if len(board) == 5 {
+			tally.Record(board, holes)
+			return
+		}
+		for i := start; i < len(deck); i++ {
+			if !used[deck[i].Label] {
+				dealBoard(i+1, append(board, deck[i]))
+			}
+		}
+	}
+
+	var dealHoles func(player int)
+	dealHoles = func(player int) {
+		if player == len(combos) {
+			dealBoard(0, append(make([]PokerCard, 0, 5), board...))
+			return
+		}
+		for _, combo := range combos[player] {
+			if used[combo[0].Label] || used[combo[1].Label] {
+				continue
+			}
+			used[combo[0].Label], used[combo[1].Label] = true, true
+			holes[player] = combo
+			dealHoles(player + 1)
+			used[combo[0].Label], used[combo[1].Label] = false, false
+		}
+	}
+	dealHoles(0)
+}
+
+func sampleEquity(combos [][]equityCombo, board []PokerCard, deck []PokerCard, iterations int, source RandomSource, tally *equityTally) {
+	holes := make([]equityCombo, len(combos))
+	available := make([]PokerCard, 0, len(deck))
+	fullBoard := make([]PokerCard, 0, 5)
+
+	// Deals where the ranges collide are thrown away so every valid deal stays equally likely
+	for attempts := 0; tally.Showdowns < iterations && attempts < iterations*100; attempts++ {
+		used := make(map[string]bool)
+		collided := false
+		for player, playerCombos := range combos {
+			combo := playerCombos[source.Intn(len(playerCombos))]
+			if used[combo[0].Label] || used[combo[1].Label] {
+				collided = true
+				break
+			}
+			used[combo[0].Label], used[combo[1].Label] = true, true
+			holes[player] = combo
+		}
+		if collided {
+			continue
+		}
+
+		available = available[:0]
+		for _, card := range deck {
+			if !used[card.Label] {
+				available = append(available, card)
+			}
+		}
+		fullBoard = append(fullBoard[:0], board...)
+		for i := 0; len(fullBoard) < 5; i++ {
+			j := i + source.Intn(len(available)-i)
+			available[i], available[j] = available[j], available[i]
+			fullBoard = append(fullBoard, available[i])
+		}
+		tally.Record(fullBoard, holes)
+	}
+}
+
+func CalculatePokerEquity(equityBody EquityBody) (EquityResult, error) {
+	var result EquityResult
+
+	knownCards := append(append([]string{}, equityBody.Board...), equityBody.Dead...)
+	for _, player := range equityBody.Players {
+		knownCards = append(knownCards, player.Cards...)
+	}
+	if _, err := ParsePokerCards(knownCards); err != nil {
+		return result, err
+	}
+	known := make(map[string]bool)
+	for _, card := range knownCards {
+		known[card] = true
+	}
+
+	// Range combos that use a card already known to be out of the deck can never be dealt
+	var combos [][]equityCombo
+	possibleShowdowns := 1.0
+	rangeCards := 0
+	for _, player := range equityBody.Players {
+		var playerCombos []equityCombo
+		if player.Range == "" {
+			holeCards, _ := ParsePokerCards(player.Cards)
+			playerCombos = append(playerCombos, equityCombo{holeCards[0], holeCards[1]})
+		} else {
+			rangeCombos, err := ParsePokerRange(player.Range)
+			if err != nil {
+				return result, err
+			}
+			for _, combo := range rangeCombos {
+				if !known[combo[0]] && !known[combo[1]] {
+					holeCards, _ := ParsePokerCards(combo)
+					playerCombos = append(playerCombos, equityCombo{holeCards[0], holeCards[1]})
+				}
+			}
+			rangeCards += 2
+		}
+		if len(playerCombos) == 0 {
+			return result, fmt.Errorf("player %q has no hands left in range", player.ID)
+		}
+		combos = append(combos, playerCombos)
+		possibleShowdowns *= float64(len(playerCombos))
+	}
+
+	var deck []PokerCard
+	for _, rank := range ranks {
+		for _, suit := range suits {
+			label := rank.Label + suit.Label
+			if !known[label] {
+				deck = append(deck, NewPokerCard(label))
+			}
+		}
+	}
+	board, _ := ParsePokerCards(equityBody.Board)
+	possibleShowdowns *= binomial(len(deck)-rangeCards, 5-len(board))
+
+	tally := newEquityTally(len(equityBody.Players))
+	if possibleShowdowns*float64(len(combos)) <= MaxExactEquityEvaluations {
+		result.Method = EquityMethodExact
+		enumerateEquity(combos, board, deck, tally)
+	} else {
+		result.Method = EquityMethodMonteCarlo
+		result.Seed = equityBody.Seed
+		sampleEquity(combos, board, deck, equityBody.Iterations, NewSeededSource(*equityBody.Seed), tally)
+	}
+	if tally.Showdowns == 0 {
+		return result, fmt.Errorf("the players' hands cannot all be dealt together")
+	}
+
+	result.Showdowns = tally.Showdowns
+	showdowns := float64(tally.Showdowns)
+	for i, player := range equityBody.Players {
+		playerEquity := PlayerEquity{
+			ID:     player.ID,
+			Win:    tally.Wins[i] / showdowns,
+			Tie:    tally.Ties[i] / showdowns,
+			Equity: tally.Equity[i] / showdowns,
+		}
+		if result.Method == EquityMethodMonteCarlo {
+			// 95% normal interval from the spread of each deal's share of the pot
+			variance := math.Max(tally.EquitySquares[i]/showdowns-playerEquity.Equity*playerEquity.Equity, 0)
+			margin := 1.96 * math.Sqrt(variance/showdowns)
+			playerEquity.ConfidenceInterval = []float64{math.Max(playerEquity.Equity-margin, 0), math.Min(playerEquity.Equity+margin, 1)}
+		}
+		result.Players = append(result.Players, playerEquity)
+	}
+	return result, nil
+}
+
+// Handlers
+
+func GetPokerEquityHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var equityBody EquityBody
+	err := json.NewDecoder(r.Body).Decode(&equityBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	validationErr := ValidateEquityBody(&equityBody)
+	if validationErr != nil {
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, equityErr := CalculatePokerEquity(equityBody)
+	if equityErr != nil {
+		http.Error(w, equityErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func equityForTest(t *testing.T, equityBody EquityBody) EquityResult {
+	if err := ValidateEquityBody(&equityBody); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	result, err := CalculatePokerEquity(equityBody)
+	if err != nil {
+		t.Fatalf("unexpected equity error: %v", err)
+	}
+	return result
+}
+
+func TestPokerRanges(t *testing.T) {
+	cases := map[string]int{
+		"AA":           6,
+		"AKs":          4,
+		"AKo":          12,
+		"AK":           16,
+		"QQ+":          18,
+		"ATs+":         16,
+		"TT-77":        24,
+		"A5s-A2s":      16,
+		"AHKH":         1,
+		"KK+, AKs, AA": 16,
+	}
+	for text, expected := range cases {
+		combos, err := ParsePokerRange(text)
+		if err != nil || len(combos) != expected {
+			t.Errorf("range %q expected to have %d combos, got %d", text, expected, len(combos))
+		}
+	}
+
+	for _, text := range []string{"AAs", "AX", "QQ+-JJ", "AKs-KQs", ""} {
+		if _, err := ParsePokerRange(text); err == nil {
+			t.Errorf("range %q expected to be rejected", text)
+		}
+	}
+}
+
+func TestPokerEquity(t *testing.T) {
+	result := equityForTest(t, EquityBody{
+		Players: []EquityPlayerBody{{ID: "aces", Cards: []string{"AS", "AH"}}, {ID: "kings", Cards: []string{"KS", "KH"}}},
+		Board:   []string{"2C", "7D", "9H"},
+	})
+	if result.Method != EquityMethodExact || result.Showdowns != 990 {
+		t.Fatalf("two cards to come expected to be enumerated exactly")
+	}
+	// Kings need one of the two remaining kings or running straight and flush cards
+	if math.Abs(result.Players[0].Equity+result.Players[1].Equity-1) > 1e-9 || result.Players[1].Equity < 0.08 || result.Players[1].Equity > 0.1 {
+		t.Errorf("kings expected to have about 9%% on the flop, got %f", result.Players[1].Equity)
+	}
+	if result.Players[0].ConfidenceInterval != nil {
+		t.Errorf("exact results expected to have no confidence interval")
+	}
+
+	result = equityForTest(t, EquityBody{
+		Players: []EquityPlayerBody{{ID: "a", Cards: []string{"AS", "KS"}}, {ID: "b", Cards: []string{"AD", "KD"}}},
+		Board:   []string{"2C", "7H", "9H", "JC", "3S"},
+	})
+	if result.Players[0].Tie != 1 || result.Players[0].Equity != 0.5 {
+		t.Errorf("identical hands expected to split the pot")
+	}
+
+	board := []string{"AS", "2H", "3D", "4C", "9S"}
+	players := []ShowdownPlayerBody{{ID: "wheel", Cards: []string{"5H", "KD"}}, {ID: "six", Cards: []string{"5D", "6S"}}}
+	showdown, _ := CalculateShowdown(board, players)
+	result = equityForTest(t, EquityBody{
+		Players: []EquityPlayerBody{{ID: "wheel", Cards: players[0].Cards}, {ID: "six", Cards: players[1].Cards}},
+		Board:   board,
+	})
+	if len(showdown.Winners) != 1 || showdown.Winners[0] != "six" || result.Players[1].Win != 1 {
+		t.Errorf("equity expected to rank hands the same way as the showdown")
+	}
+
+	result = equityForTest(t, EquityBody{
+		Players: []EquityPlayerBody{{ID: "ace", Cards: []string{"AH", "2D"}}, {ID: "trey", Cards: []string{"3H", "2C"}}},
+		Board:   []string{"5S", "5H", "4D", "4C", "9S"},
+	})
+	if result.Players[0].Win != 1 {
+		t.Errorf("ace kicker with two pair on the board expected to win every showdown")
+	}
+
+	seed := int64(7)
+	result = equityForTest(t, EquityBody{
+		Players:    []EquityPlayerBody{{ID: "aces", Cards: []string{"AS", "AH"}}, {ID: "kings", Cards: []string{"KS", "KH"}}},
+		Iterations: 20000,
+		Seed:       &seed,
+	})
+	interval := result.Players[0].ConfidenceInterval
+	if result.Method != EquityMethodMonteCarlo || result.Showdowns != 20000 || len(interval) != 2 {
+		t.Fatalf("preflop equity expected to be sampled")
+	}
+	// Suited the same way the aces hold about 82.6%, the kings make no flush the aces can't match
+	if interval[0] > 0.826 || interval[1] < 0.826 || interval[1]-interval[0] > 0.02 {
+		t.Errorf("aces expected to have about 82.6%% against kings, got %f to %f", interval[0], interval[1])
+	}
+
+	result = equityForTest(t, EquityBody{
+		Players: []EquityPlayerBody{{ID: "hero", Cards: []string{"AS", "AH"}}, {ID: "villain", Range: "KK+"}},
+		Board:   []string{"2C", "7D", "9H", "3S"},
+		Dead:    []string{"KD"},
+	})
+	// Only AD-AC and the three kings combos without KD are left in the villain's range
+	if result.Method != EquityMethodExact || result.Showdowns != 4*43 {
+		t.Errorf("range against known cards expected to be enumerated exactly, got %d showdowns", result.Showdowns)
+	}
+
+	if ValidateEquityBody(&EquityBody{Players: []EquityPlayerBody{{ID: "a", Cards: []string{"AS", "AH"}}}}) == nil {
+		t.Errorf("a single player expected to be rejected")
+	}
+	if ValidateEquityBody(&EquityBody{Players: []EquityPlayerBody{{ID: "a", Cards: []string{"AS", "AH"}}, {ID: "b", Cards: []string{"KS", "KH"}}}, Board: []string{"2C"}}) == nil {
+		t.Errorf("a one card board expected to be rejected")
+	}
+	if _, err := CalculatePokerEquity(EquityBody{Players: []EquityPlayerBody{{ID: "a", Cards: []string{"AS", "AH"}}, {ID: "b", Range: "AA"}}, Dead: []string{"AD", "AC"}}); err == nil {
+		t.Errorf("a range with every combo dead expected to be rejected")
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Structs
+
+type PokerPotPlayer struct {
+	ID           string `json:"id"`
+	Contribution int    `json:"contribution"`
+	Folded       bool   `json:"folded"`
+	HandValue    uint32 `json:"handValue"`
+}
+
+type PokerPot struct {
+	Amount   int            `json:"amount"`
+	Eligible []string       `json:"eligible"`
+	Winners  []string       `json:"winners"`
+	Payouts  map[string]int `json:"payouts"`
+	OddChips int            `json:"oddChips"`
+	Uncalled bool           `json:"uncalled,omitempty"`
+}
+
+type PokerPotsBody struct {
+	Players []PokerPotPlayer `json:"players"`
+}
+
+type PokerPotsResponseBody struct {
+	Pots    []PokerPot     `json:"pots"`
+	Payouts map[string]int `json:"payouts"`
+}
+
+// Functions
+
+func ValidatePokerPotPlayers(players []PokerPotPlayer) error {
+	seenIDs := make(map[string]bool)
+	active := 0
+	for _, player := range players {
+		if player.ID == "" {
+			return fmt.Errorf("every player needs an id")
+		}
+		if seenIDs[player.ID] {
+			return fmt.Errorf("duplicate player id %q", player.ID)
+		}
+		seenIDs[player.ID] = true
+		if player.Contribution < 0 {
+			return fmt.Errorf("player %q cannot contribute a negative amount", player.ID)
+		}
+		if !player.Folded {
+			active++
+		}
+	}
+	if active == 0 {
+		return fmt.Errorf("at least one player must still be in the hand")
+	}
+	return nil
+}
+
+func insertLevel(levels []int, level int) []int {
+	for i, existing := range levels {
+		if existing == level {
+			return levels
+		}
+		if existing > level {
+			return append(levels[:i], append([]int{level}, levels[i:]...)...)
+		}
+	}
+	return append(levels, level)
+}
+
+func CalculatePokerPots(players []PokerPotPlayer) []PokerPot {
+	// Every distinct amount put in by a player still in the hand caps a pot only the players who reached it can win
+	var levels []int
+	for _, player := range players {
+		if !player.Folded {
+			levels = insertLevel(levels, player.Contribution)
+		}
+	}
+
+	pots := []PokerPot{}
+	previousLevel := 0
+	for i, level := range levels {
+		pot := PokerPot{Eligible: []string{}, Winners: []string{}, Payouts: make(map[string]int)}
+		contributors := 0
+		for _, player := range players {
+			contribution := player.Contribution
+			// Folded chips above the highest level are dead money in the last pot
+			if contribution > level && i < len(levels)-1 {
+				contribution = level
+			}
+			if contribution > previousLevel {
+				pot.Amount += contribution - previousLevel
+				contributors++
+			}
+			if !player.Folded && player.Contribution >= level {
+				pot.Eligible = append(pot.Eligible, player.ID)
+			}
+		}
+		previousLevel = level
+		if pot.Amount == 0 {
+			continue
+		}
+		pot.Uncalled = contributors == 1 && len(pot.Eligible) == 1
+
+		var bestValue uint32
+		for _, player := range players {
+			if player.Folded || player.Contribution < level {
+				continue
+			}
+			if len(pot.Winners) == 0 || player.HandValue > bestValue {
+				bestValue = player.HandValue
+				pot.Winners = []string{player.ID}
+			} else if player.HandValue == bestValue {
+				pot.Winners = append(pot.Winners, player.ID)
+			}
+		}
+
+		// Odd chips go one at a time to the earliest winners in list order
+		share := pot.Amount / len(pot.Winners)
+		pot.OddChips = pot.Amount % len(pot.Winners)
+		for j, winner := range pot.Winners {
+			pot.Payouts[winner] = share
+			if j < pot.OddChips {
+				pot.Payouts[winner]++
+			}
+		}
+		pots = append(pots, pot)
+	}
+	return pots
+}
+
+func MakePokerPotsResponseBody(pots []PokerPot) PokerPotsResponseBody {
+	responseBody := PokerPotsResponseBody{Pots: pots, Payouts: make(map[string]int)}
+	for _, pot := range pots {
+		for id, payout := range pot.Payouts {
+			responseBody.Payouts[id] += payout
+		}
+	}
+	return responseBody
+}
+
+// Handlers
+
+func GetPokerPotsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var potsBody PokerPotsBody
+	err := json.NewDecoder(r.Body).Decode(&potsBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	validationErr := ValidatePokerPotPlayers(potsBody.Players)
+	if validationErr != nil {
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(MakePokerPotsResponseBody(CalculatePokerPots(potsBody.Players)))
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestPokerSidePots(t *testing.T) {
+	pots := CalculatePokerPots([]PokerPotPlayer{
+		{ID: "alice", Contribution: 100, HandValue: 1},
+		{ID: "bob", Contribution: 50, HandValue: 2},
+		{ID: "carol", Contribution: 20, HandValue: 3},
+	})
+	if len(pots) != 3 {
+		t.Fatalf("main pot, side pot and uncalled chips expected")
+	}
+	if pots[0].Amount != 60 || len(pots[0].Eligible) != 3 || pots[0].Payouts["carol"] != 60 {
+		t.Errorf("main pot of 60 expected to go to carol")
+	}
+	if pots[1].Amount != 60 || len(pots[1].Eligible) != 2 || pots[1].Payouts["bob"] != 60 {
+		t.Errorf("side pot of 60 expected to go to bob")
+	}
+	if pots[2].Amount != 50 || !pots[2].Uncalled || pots[2].Payouts["alice"] != 50 {
+		t.Errorf("uncalled 50 expected to go back to alice")
+	}
+
+	pots = CalculatePokerPots([]PokerPotPlayer{
+		{ID: "alice", Contribution: 31, Folded: true, HandValue: 9},
+		{ID: "bob", Contribution: 20, HandValue: 5},
+		{ID: "carol", Contribution: 20, HandValue: 5},
+	})
+	if len(pots) != 1 || pots[0].Amount != 71 || pots[0].Uncalled {
+		t.Errorf("folded chips expected to be dead money in the pot")
+	}
+	if pots[0].OddChips != 1 || pots[0].Payouts["bob"] != 36 || pots[0].Payouts["carol"] != 35 {
+		t.Errorf("odd chip expected to go to the earliest winner")
+	}
+	if MakePokerPotsResponseBody(pots).Payouts["alice"] != 0 {
+		t.Errorf("folded player expected to win nothing")
+	}
+
+	board := []string{"5S", "5H", "4D", "4C", "9S"}
+	handValue := func(holeCards ...string) uint32 {
+		cards, _ := ParsePokerCards(append(append([]string{}, board...), holeCards...))
+		return EvaluatePokerHand(cards).Value
+	}
+	pots = CalculatePokerPots([]PokerPotPlayer{
+		{ID: "alice", Contribution: 20, HandValue: handValue("AH", "2D")},
+		{ID: "bob", Contribution: 50, HandValue: handValue("3H", "2C")},
+		{ID: "carol", Contribution: 50, HandValue: handValue("KH", "2S")},
+	})
+	if pots[0].Payouts["alice"] != 60 || pots[1].Payouts["carol"] != 60 {
+		t.Errorf("ace kicker expected to take the main pot and king kicker the side pot")
+	}
+
+	if ValidatePokerPotPlayers([]PokerPotPlayer{{ID: "alice", Contribution: 10, Folded: true}}) == nil {
+		t.Errorf("a hand with everyone folded expected to be rejected")
+	}
+	if ValidatePokerPotPlayers([]PokerPotPlayer{{ID: "alice"}, {ID: "alice"}}) == nil {
+		t.Errorf("duplicate players expected to be rejected")
+	}
+	if ValidatePokerPotPlayers([]PokerPotPlayer{{ID: "alice", Contribution: -1}}) == nil {
+		t.Errorf("negative contributions expected to be rejected")
+	}
+}
@@ -0,0 +1,640 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Structs
+
+type PokerSeat struct {
+	PlayerID    string   `json:"playerId"`
+	Chips       int      `json:"chips"`
+	HoleCards   []string `json:"holeCards,omitempty"`
+	Bet         int      `json:"bet"`
+	Contributed int      `json:"contributed"`
+	InHand      bool     `json:"inHand"`
+	Folded      bool     `json:"folded"`
+	AllIn       bool     `json:"allIn"`
+	Acted       bool     `json:"-"`
+	ActedAt     int      `json:"-"`
+}
+
+type PokerHandResult struct {
+	Winnings map[string]int         `json:"winnings"`
+	Pots     []PokerPot             `json:"pots"`
+	Showdown []ShowdownPlayerResult `json:"showdown,omitempty"`
+}
+
+type PokerTable struct {
+	ID             string
+	SmallBlind     int
+	BigBlind       int
+	Seats          []*PokerSeat
+	Shoe           *Shoe
+	Status         string
+	HandNumber     int
+	Button         int
+	ActingSeat     int
+	Board          []string
+	CurrentBet     int
+	MinRaise       int
+	FullRaiseLevel int
+	Result         *PokerHandResult
+}
+
+type PokerPlayerBody struct {
+	ID    string `json:"id"`
+	Chips int    `json:"chips"`
+}
+
+type PokerTableBody struct {
+	SmallBlind int               `json:"smallBlind"`
+	BigBlind   int               `json:"bigBlind"`
+	Players    []PokerPlayerBody `json:"players"`
+	RNG        string            `json:"rng"`
+	Seed       *int64            `json:"seed"`
+}
+
+type PokerActionBody struct {
+	PlayerID string `json:"playerId"`
+	Action   string `json:"action"`
+	Amount   int    `json:"amount"`
+}
+
+type PokerTableResponseBody struct {
+	ID           string           `json:"id"`
+	Status       string           `json:"status"`
+	SmallBlind   int              `json:"smallBlind"`
+	BigBlind     int              `json:"bigBlind"`
+	HandNumber   int              `json:"handNumber"`
+	Button       int              `json:"button"`
+	ActingPlayer string           `json:"actingPlayer,omitempty"`
+	Board        []string         `json:"board"`
+	Pot          int              `json:"pot"`
+	CurrentBet   int              `json:"currentBet"`
+	MinRaiseTo   int              `json:"minRaiseTo"`
+	Seats        []PokerSeat      `json:"seats"`
+	Result       *PokerHandResult `json:"result,omitempty"`
+}
+
+// Package Variables
+
+var PokerTables = make(map[string]*PokerTable)
+
+const MaxPokerSeats = 10
+
+const PokerStatusWaiting = "WAITING"
+const PokerStatusPreflop = "PREFLOP"
+const PokerStatusFlop = "FLOP"
+const PokerStatusTurn = "TURN"
+const PokerStatusRiver = "RIVER"
+const PokerStatusComplete = "COMPLETE"
+
+// Functions
+
+func NewPokerTable(shoe *Shoe, smallBlind int, bigBlind int) *PokerTable {
+	return &PokerTable{
+		SmallBlind: smallBlind,
+		BigBlind:   bigBlind,
+		Shoe:       shoe,
+		Status:     PokerStatusWaiting,
+		Button:     -1,
+		ActingSeat: -1,
+		Board:      []string{},
+	}
+}
+
+func ValidatePokerTableBody(tableBody *PokerTableBody) error {
+	if tableBody.SmallBlind <= 0 || tableBody.BigBlind < tableBody.SmallBlind {
+		return fmt.Errorf("blinds must be positive with the big blind at least the small blind")
+	}
+	if len(tableBody.Players) > MaxPokerSeats {
+		return fmt.Errorf("a table seats at most %d players", MaxPokerSeats)
+	}
+
+	rngBody := RNGBody{Mode: tableBody.RNG, Seed: tableBody.Seed}
+	if err := ValidateRNGBody(&rngBody); err != nil {
+		return err
+	}
+	tableBody.RNG, tableBody.Seed = rngBody.Mode, rngBody.Seed
+	return nil
+}
+
+func (table *PokerTable) IsBetting() bool {
+	switch table.Status {
+	case PokerStatusPreflop, PokerStatusFlop, PokerStatusTurn, PokerStatusRiver:
+		return true
+	}
+	return false
+}
+
+func (table *PokerTable) SeatPlayer(id string, chips int) error {
+	if id == "" {
+		return fmt.Errorf("a player id is required")
+	}
+	if chips <= 0 {
+		return fmt.Errorf("player %q must bring chips to the table", id)
+	}
+	if len(table.Seats) >= MaxPokerSeats {
+		return fmt.Errorf("the table is full")
+	}
+	for _, seat := range table.Seats {
+		if seat.PlayerID == id {
+			return fmt.Errorf("player %q is already seated", id)
+		}
+	}
+
+	// Players who sit down mid-hand wait for the next deal
+	table.Seats = append(table.Seats, &PokerSeat{PlayerID: id, Chips: chips})
+	return nil
+}
+
+func (table *PokerTable) NextSeat(from int, matches func(seat *PokerSeat) bool) int {
+	for i := 1; i <= len(table.Seats); i++ {
+		index := (from + i) % len(table.Seats)
+		if matches(table.Seats[index]) {
+			return index
+		}
+	}
+	return -1
+}
+
+func (seat *PokerSeat) CanAct() bool {
+	return seat.InHand && !seat.Folded && !seat.AllIn
+}
+
+func (seat *PokerSeat) Commit(amount int) {
+	seat.Chips -= amount
+	seat.Bet += amount
+	seat.Contributed += amount
+	if seat.Chips == 0 {
+		seat.AllIn = true
+	}
+}
+
+func (table *PokerTable) Pot() int {
+	pot := 0
+	for _, seat := range table.Seats {
+		pot += seat.Contributed
+	}
+	return pot
+}
+
+func (table *PokerTable) StartHand() error {
+	if table.IsBetting() {
+		return fmt.Errorf("a hand is already in progress")
+	}
+
+	players := 0
+	for _, seat := range table.Seats {
+		if seat.Chips > 0 {
+			players++
+		}
+	}
+	if players < 2 {
+		return fmt.Errorf("at least two players with chips are required")
+	}
+	for _, seat := range table.Seats {
+		*seat = PokerSeat{PlayerID: seat.PlayerID, Chips: seat.Chips, InHand: seat.Chips > 0}
+	}
+
+	inHand := func(seat *PokerSeat) bool { return seat.InHand }
+	table.HandNumber++
+	table.Status = PokerStatusPreflop
+	table.Board = []string{}
+	table.Result = nil
+	table.Shoe.Reset()
+	// Every hand is a fresh shuffle and nothing reads back an earlier hand's record, so only the current one is kept
+	table.Shoe.Shuffles = append([]ShuffleRecord{}, table.Shoe.Shuffles[len(table.Shoe.Shuffles)-1])
+	table.Button = table.NextSeat(table.Button, inHand)
+
+	// Heads-up the button posts the small blind and acts first before the flop
+	smallBlindSeat := table.Button
+	if players > 2 {
+		smallBlindSeat = table.NextSeat(table.Button, inHand)
+	}
+	bigBlindSeat := table.NextSeat(smallBlindSeat, inHand)
+	table.Seats[smallBlindSeat].Commit(minInt(table.SmallBlind, table.Seats[smallBlindSeat].Chips))
+	table.Seats[bigBlindSeat].Commit(minInt(table.BigBlind, table.Seats[bigBlindSeat].Chips))
+	table.CurrentBet = table.BigBlind
+	table.MinRaise = table.BigBlind
+	table.FullRaiseLevel = table.BigBlind
+
+	for round := 0; round < 2; round++ {
+		seatIndex := table.Button
+		for i := 0; i < players; i++ {
+			seatIndex = table.NextSeat(seatIndex, inHand)
+			seat := table.Seats[seatIndex]
+			seat.HoleCards = append(seat.HoleCards, table.DrawCard())
+		}
+	}
+
+	table.ActingSeat = bigBlindSeat
+	table.Advance()
+	return nil
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (table *PokerTable) DrawCard() string {
+	card := table.Shoe.DrawCard()
+	return card.String()
+}
+
+func (table *PokerTable) Act(playerID string, action string, amount int) error {
+	if !table.IsBetting() {
+		return fmt.Errorf("no hand is waiting for an action")
+	}
+	seat := table.Seats[table.ActingSeat]
+	if seat.PlayerID != playerID {
+		return fmt.Errorf("it is %s's turn to act", seat.PlayerID)
+	}
+
+	toCall := table.CurrentBet - seat.Bet
+	// A short all-in raise does not reopen the betting to players who have already acted
+	canRaise := !seat.Acted || table.FullRaiseLevel > seat.ActedAt
+	raiseTo := func(total int) {
+		if total-table.CurrentBet >= table.MinRaise {
+			table.MinRaise = total - table.CurrentBet
+			table.FullRaiseLevel = total
+		}
+		table.CurrentBet = total
+		seat.Commit(total - seat.Bet)
+	}
+
+	switch action {
+	case "CHECK":
+		if toCall > 0 {
+			return fmt.Errorf("cannot check facing a bet of %d", table.CurrentBet)
+		}
+	case "CALL":
+		if toCall == 0 {
+			return fmt.Errorf("there is no bet to call")
+		}
+		seat.Commit(minInt(toCall, seat.Chips))
+	case "FOLD":
+		seat.Folded = true
+	case "BET":
+		if table.CurrentBet > 0 {
+			return fmt.Errorf("there is already a bet, raise instead")
+		}
+		if amount < table.BigBlind {
+			return fmt.Errorf("a bet must be at least the big blind of %d", table.BigBlind)
+		}
+		if amount > seat.Chips {
+			return fmt.Errorf("a bet of %d is more than the %d chips behind", amount, seat.Chips)
+		}
+		raiseTo(amount)
+	case "RAISE":
+		if table.CurrentBet == 0 {
+			return fmt.Errorf("there is no bet to raise, bet instead")
+		}
+		if !canRaise {
+			return fmt.Errorf("the betting has not been reopened by a full raise")
+		}
+		if amount < table.CurrentBet+table.MinRaise {
+			return fmt.Errorf("a raise must be to at least %d", table.CurrentBet+table.MinRaise)
+		}
+		if amount-seat.Bet > seat.Chips {
+			return fmt.Errorf("a raise to %d is more than the %d chips behind", amount, seat.Chips)
+		}
+		raiseTo(amount)
+	case "ALL_IN":
+		total := seat.Bet + seat.Chips
+		if total <= table.CurrentBet {
+			seat.Commit(seat.Chips)
+			break
+		}
+		if !canRaise {
+			return fmt.Errorf("the betting has not been reopened by a full raise")
+		}
+		raiseTo(total)
+	default:
+		return fmt.Errorf("unknown action %q", action)
+	}
+
+	seat.Acted = true
+	seat.ActedAt = table.CurrentBet
+	table.Advance()
+	return nil
+}
+
+func (table *PokerTable) BettingComplete() bool {
+	var canAct []*PokerSeat
+	for _, seat := range table.Seats {
+		if seat.CanAct() {
+			canAct = append(canAct, seat)
+		}
+	}
+
+	// Nobody is left to bet against a lone player who has matched the bet
+	if len(canAct) == 0 || (len(canAct) == 1 && canAct[0].Bet >= table.CurrentBet) {
+		return true
+	}
+	for _, seat := range canAct {
+		if !seat.Acted || seat.Bet < table.CurrentBet {
+			return false
+		}
+	}
+	return true
+}
+
+func (table *PokerTable) Advance() {
+	remaining := 0
+	for _, seat := range table.Seats {
+		if seat.InHand && !seat.Folded {
+			remaining++
+		}
+	}
+
+	// The last player left wins every pot without showing
+	if remaining == 1 {
+		table.AwardPots(nil)
+		return
+	}
+	if table.BettingComplete() {
+		table.NextStreet()
+		return
+	}
+
+	table.ActingSeat = table.NextSeat(table.ActingSeat, func(seat *PokerSeat) bool {
+		return seat.CanAct() && (!seat.Acted || seat.Bet < table.CurrentBet)
+	})
+}
+
+func (table *PokerTable) NextStreet() {
+	for table.IsBetting() {
+		for _, seat := range table.Seats {
+			seat.Bet = 0
+			seat.Acted = false
+			seat.ActedAt = 0
+		}
+		table.CurrentBet = 0
+		table.MinRaise = table.BigBlind
+		table.FullRaiseLevel = 0
+
+		// Each street is dealt after a burn card
+		switch table.Status {
+		case PokerStatusPreflop:
+			table.DrawCard()
+			table.Board = append(table.Board, table.DrawCard(), table.DrawCard(), table.DrawCard())
+			table.Status = PokerStatusFlop
+		case PokerStatusFlop:
+			table.DrawCard()
+			table.Board = append(table.Board, table.DrawCard())
+			table.Status = PokerStatusTurn
+		case PokerStatusTurn:
+			table.DrawCard()
+			table.Board = append(table.Board, table.DrawCard())
+			table.Status = PokerStatusRiver
+		case PokerStatusRiver:
+			table.Showdown()
+			return
+		}
+
+		// When at most one player can still bet the rest of the board is run out
+		if !table.BettingComplete() {
+			table.ActingSeat = table.NextSeat(table.Button, func(seat *PokerSeat) bool { return seat.CanAct() })
+			return
+		}
+	}
+}
+
+func (table *PokerTable) EndHand() {
+	table.Status = PokerStatusComplete
+	table.ActingSeat = -1
+	for _, seat := range table.Seats {
+		seat.Bet = 0
+	}
+}
+
+func (table *PokerTable) SeatsFromButton() []*PokerSeat {
+	var order []*PokerSeat
+	seatIndex := table.Button
+	for range table.Seats {
+		seatIndex = (seatIndex + 1) % len(table.Seats)
+		if table.Seats[seatIndex].InHand {
+			order = append(order, table.Seats[seatIndex])
+		}
+	}
+	return order
+}
+
+func (table *PokerTable) Showdown() {
+	var players []ShowdownPlayerBody
+	for _, seat := range table.SeatsFromButton() {
+		if !seat.Folded {
+			players = append(players, ShowdownPlayerBody{ID: seat.PlayerID, Cards: seat.HoleCards})
+		}
+	}
+
+	showdown, _ := CalculateShowdown(table.Board, players)
+	table.AwardPots(showdown.Players)
+}
+
+func (table *PokerTable) AwardPots(showdown []ShowdownPlayerResult) {
+	values := make(map[string]uint32)
+	for _, player := range showdown {
+		values[player.ID] = player.Hand.Value
+	}
+
+	// Players are listed from the left of the button so odd chips go to the earliest winners in that order
+	seats := make(map[string]*PokerSeat)
+	var players []PokerPotPlayer
+	for _, seat := range table.SeatsFromButton() {
+		seats[seat.PlayerID] = seat
+		players = append(players, PokerPotPlayer{ID: seat.PlayerID, Contribution: seat.Contributed, Folded: seat.Folded, HandValue: values[seat.PlayerID]})
+	}
+
+	pots := CalculatePokerPots(players)
+	potsResponseBody := MakePokerPotsResponseBody(pots)
+	for id, payout := range potsResponseBody.Payouts {
+		seats[id].Chips += payout
+	}
+
+	table.Result = &PokerHandResult{Winnings: potsResponseBody.Payouts, Pots: pots, Showdown: showdown}
+	table.EndHand()
+}
+
+func MakePokerTableResponseBody(table *PokerTable, viewerID string) PokerTableResponseBody {
+	responseBody := PokerTableResponseBody{
+		ID:         table.ID,
+		Status:     table.Status,
+		SmallBlind: table.SmallBlind,
+		BigBlind:   table.BigBlind,
+		HandNumber: table.HandNumber,
+		Button:     table.Button,
+		Board:      table.Board,
+		Pot:        table.Pot(),
+		CurrentBet: table.CurrentBet,
+		MinRaiseTo: table.CurrentBet + table.MinRaise,
+		Seats:      []PokerSeat{},
+		Result:     table.Result,
+	}
+	if table.IsBetting() {
+		responseBody.ActingPlayer = table.Seats[table.ActingSeat].PlayerID
+	}
+
+	// Hole cards are only shown to their owner until they are turned over at showdown
+	shownAtShowdown := table.Result != nil && table.Result.Showdown != nil
+	for _, seat := range table.Seats {
+		seatCopy := *seat
+		if seat.PlayerID != viewerID && !(shownAtShowdown && seat.InHand && !seat.Folded) {
+			seatCopy.HoleCards = nil
+		}
+		responseBody.Seats = append(responseBody.Seats, seatCopy)
+	}
+	return responseBody
+}
+
+// Handlers
+
+func CreatePokerTableHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var tableBody PokerTableBody
+	err := json.NewDecoder(r.Body).Decode(&tableBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	validationErr := ValidatePokerTableBody(&tableBody)
+	if validationErr != nil {
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	shoe := NewShoeWithSource(1, NewRandomSource(RNGBody{Mode: tableBody.RNG, Seed: tableBody.Seed}))
+	table := NewPokerTable(shoe, tableBody.SmallBlind, tableBody.BigBlind)
+	for _, player := range tableBody.Players {
+		seatErr := table.SeatPlayer(player.ID, player.Chips)
+		if seatErr != nil {
+			http.Error(w, seatErr.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	table.ID = NewRandomID()
+	PokerTables[table.ID] = table
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(MakePokerTableResponseBody(table, ""))
+}
+
+func GetPokerTableHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	tableID := vars["id"]
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	table, found := PokerTables[tableID]
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	// playerId is not authenticated, anyone who passes it sees that player's hole cards, so the table is meant to sit
+	// behind a front end that checks who is asking before forwarding the request
+	json.NewEncoder(w).Encode(MakePokerTableResponseBody(table, r.URL.Query().Get("playerId")))
+}
+
+func SeatPokerPlayerHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var playerBody PokerPlayerBody
+	err := json.NewDecoder(r.Body).Decode(&playerBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	tableID := vars["id"]
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	table, found := PokerTables[tableID]
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	seatErr := table.SeatPlayer(playerBody.ID, playerBody.Chips)
+	if seatErr != nil {
+		http.Error(w, seatErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(MakePokerTableResponseBody(table, playerBody.ID))
+}
+
+func StartPokerHandHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	tableID := vars["id"]
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	table, found := PokerTables[tableID]
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	handErr := table.StartHand()
+	if handErr != nil {
+		http.Error(w, handErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(MakePokerTableResponseBody(table, ""))
+}
+
+func PokerTableActionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var actionBody PokerActionBody
+	err := json.NewDecoder(r.Body).Decode(&actionBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	tableID := vars["id"]
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	table, found := PokerTables[tableID]
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	actionErr := table.Act(actionBody.PlayerID, actionBody.Action, actionBody.Amount)
+	if actionErr != nil {
+		http.Error(w, actionErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(MakePokerTableResponseBody(table, actionBody.PlayerID))
+}
@@ -0,0 +1,150 @@
+package main
+
+import "testing"
+
+func newPokerTableForTest(t *testing.T, shoe *Shoe, chips ...int) *PokerTable {
+	table := NewPokerTable(shoe, 1, 2)
+	for i, stack := range chips {
+		if err := table.SeatPlayer(string(rune('a'+i)), stack); err != nil {
+			t.Fatalf("unexpected seating error: %v", err)
+		}
+	}
+	if err := table.StartHand(); err != nil {
+		t.Fatalf("unexpected error starting the hand: %v", err)
+	}
+	return table
+}
+
+func actForTest(t *testing.T, table *PokerTable, playerID string, action string, amount int) {
+	if err := table.Act(playerID, action, amount); err != nil {
+		t.Fatalf("unexpected error for %s %s %d: %v", playerID, action, amount, err)
+	}
+}
+
+func TestPokerTableBlinds(t *testing.T) {
+	table := newPokerTableForTest(t, NewShoeWithSource(1, NewSeededSource(1)), 100, 100)
+	if table.Seats[0].Bet != 1 || table.Seats[1].Bet != 2 || table.Seats[table.ActingSeat].PlayerID != "a" {
+		t.Errorf("heads-up button expected to post the small blind and act first")
+	}
+	if len(table.Seats[0].HoleCards) != 2 || len(table.Seats[1].HoleCards) != 2 {
+		t.Errorf("two hole cards expected for each player")
+	}
+
+	actForTest(t, table, "a", "RAISE", 6)
+	actForTest(t, table, "b", "FOLD", 0)
+	if table.Status != PokerStatusComplete || table.Seats[0].Chips != 102 || table.Seats[1].Chips != 98 {
+		t.Errorf("uncontested pot expected to go to the raiser")
+	}
+
+	if err := table.StartHand(); err != nil {
+		t.Fatalf("unexpected error starting the second hand: %v", err)
+	}
+	if table.Button != 1 || table.Seats[1].Bet != 1 || table.Seats[0].Bet != 2 {
+		t.Errorf("button and blinds expected to move")
+	}
+
+	if len(table.Shoe.Shuffles) != 1 || table.Shoe.Shuffles[0].Number != 3 {
+		t.Errorf("table shoe expected to keep only the current hand's shuffle record")
+	}
+
+	responseBody := MakePokerTableResponseBody(table, "a")
+	if len(responseBody.Seats[0].HoleCards) != 2 || len(responseBody.Seats[1].HoleCards) != 0 {
+		t.Errorf("only the viewer's hole cards expected to be shown")
+	}
+}
+
+func TestPokerTableActions(t *testing.T) {
+	table := newPokerTableForTest(t, NewShoeWithSource(1, NewSeededSource(1)), 100, 100, 100)
+	if table.Seats[table.ActingSeat].PlayerID != "a" {
+		t.Fatalf("the button expected to act first three-handed")
+	}
+
+	if table.Act("b", "CALL", 0) == nil {
+		t.Errorf("acting out of turn expected to be rejected")
+	}
+	if table.Act("a", "CHECK", 0) == nil {
+		t.Errorf("checking facing the big blind expected to be rejected")
+	}
+	if table.Act("a", "BET", 4) == nil {
+		t.Errorf("betting into the big blind expected to be rejected")
+	}
+	if table.Act("a", "RAISE", 3) == nil {
+		t.Errorf("raising less than the big blind expected to be rejected")
+	}
+	actForTest(t, table, "a", "RAISE", 6)
+	if table.Act("b", "RAISE", 9) == nil {
+		t.Errorf("raising less than the previous raise expected to be rejected")
+	}
+	actForTest(t, table, "b", "CALL", 0)
+	actForTest(t, table, "c", "CALL", 0)
+
+	if table.Status != PokerStatusFlop || len(table.Board) != 3 || table.Seats[table.ActingSeat].PlayerID != "b" {
+		t.Fatalf("flop expected with the small blind first to act")
+	}
+	if table.Act("b", "BET", 1) == nil {
+		t.Errorf("betting less than the big blind expected to be rejected")
+	}
+	actForTest(t, table, "b", "CHECK", 0)
+	actForTest(t, table, "c", "BET", 10)
+	actForTest(t, table, "a", "FOLD", 0)
+	actForTest(t, table, "b", "CALL", 0)
+
+	if table.Status != PokerStatusTurn || len(table.Board) != 4 || table.Pot() != 38 {
+		t.Errorf("turn expected with a pot of 38")
+	}
+}
+
+func TestPokerTableShortAllIn(t *testing.T) {
+	table := newPokerTableForTest(t, NewShoeWithSource(1, NewSeededSource(1)), 100, 15, 100)
+
+	actForTest(t, table, "a", "RAISE", 10)
+	actForTest(t, table, "b", "ALL_IN", 0)
+	if table.CurrentBet != 15 || table.MinRaise != 8 {
+		t.Errorf("short all-in expected to raise the bet without changing the minimum raise")
+	}
+	actForTest(t, table, "c", "CALL", 0)
+	if table.Act("a", "RAISE", 30) == nil || table.Act("a", "ALL_IN", 0) == nil {
+		t.Errorf("short all-in expected not to reopen the betting")
+	}
+	actForTest(t, table, "a", "CALL", 0)
+	if table.Status != PokerStatusFlop || table.Pot() != 45 {
+		t.Errorf("flop expected with a pot of 45")
+	}
+}
+
+func TestPokerTableShowdown(t *testing.T) {
+	shoe := newSequencedShoe([]string{"KS", "AS", "QS", "KH", "AH", "QH", "4D", "2C", "7D", "9H", "5D", "3S", "6D", "8C"})
+	table := newPokerTableForTest(t, shoe, 100, 50, 20)
+
+	actForTest(t, table, "a", "ALL_IN", 0)
+	actForTest(t, table, "b", "ALL_IN", 0)
+	actForTest(t, table, "c", "ALL_IN", 0)
+
+	if table.Status != PokerStatusComplete || len(table.Board) != 5 || table.Board[4] != "8C" {
+		t.Fatalf("all-in hand expected to run out the board to showdown")
+	}
+	if table.Seats[2].Chips != 60 || table.Seats[1].Chips != 60 || table.Seats[0].Chips != 50 {
+		t.Errorf("main pot expected for aces, side pot for kings and the uncalled chips back, got %d %d %d", table.Seats[0].Chips, table.Seats[1].Chips, table.Seats[2].Chips)
+	}
+	if len(table.Result.Pots) != 3 || !table.Result.Pots[2].Uncalled {
+		t.Errorf("main pot, side pot and uncalled chips expected")
+	}
+	if len(MakePokerTableResponseBody(table, "").Seats[0].HoleCards) != 2 {
+		t.Errorf("hole cards expected to be shown at showdown")
+	}
+}
+
+func TestPokerTableAceKicker(t *testing.T) {
+	shoe := newSequencedShoe([]string{"3H", "AH", "2C", "2D", "7D", "5S", "5H", "4D", "8D", "4C", "6D", "9S"})
+	table := newPokerTableForTest(t, shoe, 100, 100)
+
+	actForTest(t, table, "a", "ALL_IN", 0)
+	actForTest(t, table, "b", "ALL_IN", 0)
+
+	if table.Status != PokerStatusComplete || table.Board[4] != "9S" {
+		t.Fatalf("all-in hand expected to run out the board to showdown")
+	}
+	if table.Seats[0].Chips != 200 || table.Seats[1].Chips != 0 {
+		t.Errorf("ace kicker expected to win two pair on the board, got %d %d", table.Seats[0].Chips, table.Seats[1].Chips)
+	}
+}
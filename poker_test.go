@@ -0,0 +1,143 @@
+package main
+
+import (
+	"testing"
+)
+
+func evaluateForTest(t *testing.T, cardStrings []string) PokerHand {
+	cards, err := ParsePokerCards(cardStrings)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	return EvaluatePokerHand(cards)
+}
+
+func TestPokerEvaluation(t *testing.T) {
+	expectedNames := map[string][]string{
+		"Royal Flush":     {"AS", "KS", "QS", "JS", "TS", "2D", "3C"},
+		"Straight Flush":  {"9H", "8H", "7H", "6H", "5H", "AH", "KD"},
+		"Four Of A Kind":  {"7S", "7H", "7D", "7C", "2S"},
+		"Full House":      {"KS", "KH", "KD", "2C", "2S", "2H", "AD"},
+		"Flush":           {"AD", "9D", "7D", "4D", "2D", "KS"},
+		"Straight":        {"AS", "2D", "3C", "4H", "5S", "KD", "KH"},
+		"Three Of A Kind": {"QS", "QH", "QD", "2C", "7S"},
+		"Two Pair":        {"JS", "JH", "4D", "4C", "9S", "9D", "2H"},
+		"Pair":            {"TS", "TH", "4D", "6C", "9S"},
+		"High Card":       {"AS", "JH", "4D", "6C", "9S", "2D", "3H"},
+	}
+
+	for name, cards := range expectedNames {
+		hand := evaluateForTest(t, cards)
+		if hand.Name != name {
+			t.Errorf("%s expected, got %s", name, hand.Name)
+		}
+		if len(hand.Cards) != 5 {
+			t.Errorf("5 best cards expected for %s", name)
+		}
+	}
+
+	twoPair := evaluateForTest(t, []string{"JS", "JH", "4D", "4C", "9S", "9D", "2H"})
+	if twoPair.Cards[4] != "4D" && twoPair.Cards[4] != "4C" {
+		t.Errorf("third pair expected to play as kicker")
+	}
+
+	if _, err := ParsePokerCards([]string{"AS", "AS"}); err == nil {
+		t.Errorf("duplicate card error expected")
+	}
+	if _, err := ParsePokerCards([]string{"1S"}); err == nil {
+		t.Errorf("invalid card error expected")
+	}
+}
+
+func TestPokerHandOrdering(t *testing.T) {
+	orderedHands := [][]string{
+		{"AS", "JH", "4D", "6C", "9S"},
+		{"2S", "2H", "4D", "6C", "9S"},
+		{"2S", "2H", "4D", "6C", "TS"},
+		{"AS", "AH", "4D", "6C", "9S"},
+		{"AS", "AH", "4D", "4C", "9S"},
+		{"3S", "3H", "3D", "6C", "9S"},
+		{"AS", "2D", "3C", "4H", "5S"},
+		{"2D", "3C", "4H", "5S", "6S"},
+		{"TD", "JC", "QH", "KS", "AS"},
+		{"AD", "9D", "7D", "4D", "2D"},
+		{"KS", "KH", "KD", "2C", "2S"},
+		{"2S", "2H", "2D", "2C", "3S"},
+		{"AH", "2H", "3H", "4H", "5H"},
+		{"9H", "TH", "JH", "QH", "KH"},
+		{"AS", "KS", "QS", "JS", "TS"},
+	}
+
+	var previousValue uint32
+	for i, cards := range orderedHands {
+		hand := evaluateForTest(t, cards)
+		if i > 0 && hand.Value <= previousValue {
+			t.Errorf("hand %v expected to beat the previous hand", cards)
+		}
+		previousValue = hand.Value
+	}
+
+	aceKickers := [][2][]string{
+		{{"5S", "5H", "5D", "5C", "KS"}, {"5S", "5H", "5D", "5C", "AS"}},
+		{{"5S", "5H", "5D", "2C", "2S"}, {"5S", "5H", "5D", "AC", "AS"}},
+		{{"5S", "5H", "4D", "4C", "2S"}, {"5S", "5H", "4D", "4C", "AS"}},
+	}
+	for _, hands := range aceKickers {
+		if evaluateForTest(t, hands[1]).Value <= evaluateForTest(t, hands[0]).Value {
+			t.Errorf("ace in %v expected to play high", hands[1])
+		}
+	}
+
+	first := evaluateForTest(t, []string{"AS", "KH", "9D", "7C", "4S"})
+	second := evaluateForTest(t, []string{"AD", "KC", "9H", "7S", "4D"})
+	if first.Value != second.Value {
+		t.Errorf("equal hands expected to have equal values")
+	}
+}
+
+func TestPokerShowdown(t *testing.T) {
+	board := []string{"AS", "KD", "7C", "4H", "2S"}
+
+	result, err := CalculateShowdown(board, []ShowdownPlayerBody{
+		{ID: "alice", Cards: []string{"AH", "QC"}},
+		{ID: "bob", Cards: []string{"AD", "JC"}},
+		{ID: "carol", Cards: []string{"3D", "5C"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected showdown error: %v", err)
+	}
+	if len(result.Winners) != 1 || result.Winners[0] != "carol" {
+		t.Errorf("wheel straight expected to win")
+	}
+
+	result, _ = CalculateShowdown(board, []ShowdownPlayerBody{
+		{ID: "alice", Cards: []string{"AH", "QC"}},
+		{ID: "bob", Cards: []string{"AD", "JC"}},
+	})
+	if len(result.Winners) != 1 || result.Winners[0] != "alice" {
+		t.Errorf("queen kicker expected to win")
+	}
+
+	result, _ = CalculateShowdown(board, []ShowdownPlayerBody{
+		{ID: "alice", Cards: []string{"AH", "3C"}},
+		{ID: "bob", Cards: []string{"AD", "3H"}},
+	})
+	if !result.SplitPot || len(result.Winners) != 2 {
+		t.Errorf("split pot expected")
+	}
+
+	result, _ = CalculateShowdown([]string{"5S", "5H", "4D", "4C", "9S"}, []ShowdownPlayerBody{
+		{ID: "alice", Cards: []string{"AH", "2D"}},
+		{ID: "bob", Cards: []string{"3H", "2C"}},
+	})
+	if len(result.Winners) != 1 || result.Winners[0] != "alice" {
+		t.Errorf("ace kicker expected to win with two pair on the board")
+	}
+
+	_, err = CalculateShowdown(board, []ShowdownPlayerBody{
+		{ID: "alice", Cards: []string{"AS", "3C"}},
+	})
+	if err == nil {
+		t.Errorf("duplicate card error expected")
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"math/rand"
+)
+
+// Structs
+
+type RandomSource interface {
+	Intn(n int) int
+}
+
+type CryptoSource struct{}
+
+type SeededSource struct {
+	Seed int64
+	rng  *rand.Rand
+}
+
+type HashSource struct {
+	Seed    []byte
+	counter uint64
+	buffer  []byte
+}
+
+type RNGBody struct {
+	Mode string `json:"mode"`
+	Seed *int64 `json:"seed"`
+}
+
+type RNGResponseBody struct {
+	Mode string `json:"mode"`
+	Seed *int64 `json:"seed,omitempty"`
+}
+
+// Package Variables
+
+const RNGModeCrypto = "crypto"
+const RNGModeSeeded = "seeded"
+const RNGModeProvablyFair = "provablyFair"
+
+// Functions
+
+func (CryptoSource) Intn(n int) int {
+	value, err := cryptorand.Int(cryptorand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		panic(err)
+	}
+	return int(value.Int64())
+}
+
+func NewSeededSource(seed int64) *SeededSource {
+	return &SeededSource{Seed: seed, rng: rand.New(rand.NewSource(seed))}
+}
+
+func (s *SeededSource) Intn(n int) int {
+	return s.rng.Intn(n)
+}
+
+func (s *SeededSource) Int63() int64 {
+	return s.rng.Int63()
+}
+
+func NewHashSource(seed []byte) *HashSource {
+	return &HashSource{Seed: seed}
+}
+
+func (h *HashSource) Uint64() uint64 {
+	// SHA-256 in counter mode: block i is SHA-256(seed || i)
+	if len(h.buffer) < 8 {
+		counterBytes := make([]byte, 8)
+		binary.BigEndian.PutUint64(counterBytes, h.counter)
+		block := sha256.Sum256(append(append([]byte{}, h.Seed...), counterBytes...))
+		h.counter++
+		h.buffer = append(h.buffer, block[:]...)
+	}
+
+	value := binary.BigEndian.Uint64(h.buffer[:8])
+	h.buffer = h.buffer[8:]
+	return value
+}
+
+func (h *HashSource) Intn(n int) int {
+	// Reject the lowest 2^64 mod n values so every result is equally likely
+	bound := uint64(n)
+	threshold := -bound % bound
+	for {
+		value := h.Uint64()
+		if value >= threshold {
+			return int(value % bound)
+		}
+	}
+}
+
+func NewRandomSeedBytes() []byte {
+	seedBytes := make([]byte, 32)
+	if _, err := cryptorand.Read(seedBytes); err != nil {
+		panic(err)
+	}
+	return seedBytes
+}
+
+func NewRandomSeed() int64 {
+	seedBytes := make([]byte, 8)
+	if _, err := cryptorand.Read(seedBytes); err != nil {
+		panic(err)
+	}
+	return int64(binary.BigEndian.Uint64(seedBytes) >> 1)
+}
+
+func ValidateRNGBody(rngBody *RNGBody) error {
+	switch rngBody.Mode {
+	case "":
+		rngBody.Mode = RNGModeCrypto
+	case RNGModeCrypto, RNGModeSeeded:
+	default:
+		return fmt.Errorf("rng must be %q or %q", RNGModeCrypto, RNGModeSeeded)
+	}
+
+	if rngBody.Mode == RNGModeCrypto && rngBody.Seed != nil {
+		return fmt.Errorf("a seed can only be used with the %q rng", RNGModeSeeded)
+	}
+	if rngBody.Mode == RNGModeSeeded && rngBody.Seed == nil {
+		seed := NewRandomSeed()
+		rngBody.Seed = &seed
+	}
+
+	return nil
+}
+
+func NewRandomSource(rngBody RNGBody) RandomSource {
+	if rngBody.Mode == RNGModeSeeded {
+		return NewSeededSource(*rngBody.Seed)
+	}
+	return CryptoSource{}
+}
+
+func MakeRNGResponseBody(source RandomSource) RNGResponseBody {
+	if seededSource, ok := source.(*SeededSource); ok {
+		seed := seededSource.Seed
+		return RNGResponseBody{Mode: RNGModeSeeded, Seed: &seed}
+	}
+	return RNGResponseBody{Mode: RNGModeCrypto}
+}
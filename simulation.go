@@ -0,0 +1,354 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Structs
+
+type BetSpreadStep struct {
+	TrueCount float64 `json:"trueCount"`
+	Units     float64 `json:"units"`
+}
+
+type SimulationBody struct {
+	Rules       *BlackjackRules `json:"rules"`
+	Hands       int             `json:"hands"`
+	Penetration float64         `json:"penetration"`
+	BetSpread   []BetSpreadStep `json:"betSpread"`
+	Bankroll    float64         `json:"bankroll"`
+	Seed        *int64          `json:"seed"`
+}
+
+type SimulationResult struct {
+	HandsPlayed       int     `json:"handsPlayed"`
+	TotalWagered      float64 `json:"totalWagered"`
+	NetResult         float64 `json:"netResult"`
+	HouseEdge         float64 `json:"houseEdge"`
+	StandardDeviation float64 `json:"standardDeviation"`
+	WinRate           float64 `json:"winRate"`
+	LossRate          float64 `json:"lossRate"`
+	PushRate          float64 `json:"pushRate"`
+	RiskOfRuin        float64 `json:"riskOfRuin"`
+	HandsPerSecond    float64 `json:"handsPerSecond"`
+}
+
+type SimulationJob struct {
+	ID        string
+	Status    string
+	Settings  SimulationBody
+	Result    SimulationResult
+	StartedAt time.Time
+}
+
+type SimulationResponseBody struct {
+	ID          string           `json:"id"`
+	Status      string           `json:"status"`
+	Hands       int              `json:"hands"`
+	Progress    float64          `json:"progress"`
+	Rules       BlackjackRules   `json:"rules"`
+	Penetration float64          `json:"penetration"`
+	BetSpread   []BetSpreadStep  `json:"betSpread"`
+	Bankroll    float64          `json:"bankroll"`
+	Seed        int64            `json:"seed"`
+	StartedAt   time.Time        `json:"startedAt"`
+	Result      SimulationResult `json:"result"`
+}
+
+type simulationStats struct {
+	rounds     int
+	wagered    float64
+	net        float64
+	netSquared float64
+	wins       int
+	losses     int
+	pushes     int
+}
+
+// Package Variables
+
+var Simulations = make(map[string]*SimulationJob)
+
+const SimulationStatusRunning = "RUNNING"
+const SimulationStatusCompleted = "COMPLETED"
+
+const DefaultSimulationHands = 1000000
+const MaxSimulationHands = 100000000
+const DefaultSimulationBankroll = 1000
+
+// How many rounds are played between progress updates
+const simulationProgressInterval = 10000
+
+// Functions
+
+func ValidateSimulationBody(simulationBody *SimulationBody) error {
+	if simulationBody.Rules == nil {
+		rules := DefaultBlackjackRules()
+		simulationBody.Rules = &rules
+	}
+	if err := ValidateBlackjackRules(*simulationBody.Rules); err != nil {
+		return err
+	}
+
+	if simulationBody.Hands == 0 {
+		simulationBody.Hands = DefaultSimulationHands
+	}
+	if simulationBody.Hands < 1 || simulationBody.Hands > MaxSimulationHands {
+		return fmt.Errorf("hands must be between 1 and %d", MaxSimulationHands)
+	}
+
+	if simulationBody.Penetration == 0 {
+		simulationBody.Penetration = DefaultPenetration
+	}
+	if simulationBody.Penetration <= 0 || simulationBody.Penetration > 1 {
+		return fmt.Errorf("penetration must be greater than 0 and at most 1")
+	}
+
+	if len(simulationBody.BetSpread) == 0 {
+		simulationBody.BetSpread = []BetSpreadStep{{TrueCount: 0, Units: 1}}
+	}
+	for _, step := range simulationBody.BetSpread {
+		if step.Units <= 0 {
+			return fmt.Errorf("bet spread units must be positive")
+		}
+	}
+	sort.Slice(simulationBody.BetSpread, func(i, j int) bool {
+		return simulationBody.BetSpread[i].TrueCount < simulationBody.BetSpread[j].TrueCount
+	})
+
+	if simulationBody.Bankroll == 0 {
+		simulationBody.Bankroll = DefaultSimulationBankroll
+	}
+	if simulationBody.Bankroll < 0 {
+		return fmt.Errorf("bankroll cannot be negative")
+	}
+
+	if simulationBody.Seed == nil {
+		seed := NewRandomSeed()
+		simulationBody.Seed = &seed
+	}
+	return nil
+}
+
+func BetForTrueCount(betSpread []BetSpreadStep, trueCount float64) float64 {
+	// Below the lowest step the smallest bet in the spread is used
+	bet := betSpread[0].Units
+	for _, step := range betSpread {
+		if trueCount >= step.TrueCount {
+			bet = step.Units
+		}
+	}
+	return bet
+}
+
+func SimulationDecision(round *BlackjackRound) string {
+	hand := round.PlayerHands[round.ActiveHand]
+	rules := round.Rules
+
+	// Surrender is only possible on the initial hand
+	if len(round.PlayerHands) > 1 || len(hand.Cards) > 2 {
+		rules.Surrender = SurrenderNone
+	}
+	if round.CanSplit() != nil {
+		rules.MaxSplits = 0
+	}
+
+	// The simulator plays hands like T-6-A as the hard totals they are, the legacy chart treats them as soft
+	isSoft := IsSoftTotal(hand.Cards)
+	decision := CalculateStrategyDecisionForHand(hand.Cards, round.DealerCards[0], rules, isSoft)
+	canDouble := len(hand.Cards) == 2 && (len(round.PlayerHands) == 1 || rules.DoubleAfterSplit)
+	if decision == "DOUBLE" && !canDouble {
+		// Soft 18 and up stand when doubling is not an option
+		if isSoft && CalculateBlackjackValueForCards(hand.Cards) >= 18 {
+			return "STAND"
+		}
+		return "HIT"
+	}
+	return decision
+}
+
+func (stats *simulationStats) Record(bet float64, netResult float64) {
+	stats.rounds++
+	stats.wagered += bet
+	stats.net += netResult
+	stats.netSquared += netResult * netResult
+	switch {
+	case netResult > 0:
+		stats.wins++
+	case netResult < 0:
+		stats.losses++
+	default:
+		stats.pushes++
+	}
+}
+
+func (stats simulationStats) Result(bankroll float64, elapsed time.Duration) SimulationResult {
+	result := SimulationResult{HandsPlayed: stats.rounds, TotalWagered: stats.wagered, NetResult: stats.net}
+	if stats.rounds == 0 {
+		return result
+	}
+
+	rounds := float64(stats.rounds)
+	mean := stats.net / rounds
+	variance := stats.netSquared/rounds - mean*mean
+
+	result.HouseEdge = -stats.net / stats.wagered
+	result.StandardDeviation = math.Sqrt(variance)
+	result.WinRate = float64(stats.wins) / rounds
+	result.LossRate = float64(stats.losses) / rounds
+	result.PushRate = float64(stats.pushes) / rounds
+
+	// Diffusion approximation of the chance of losing the whole bankroll with no stop-win
+	result.RiskOfRuin = 1
+	if mean > 0 && variance > 0 {
+		result.RiskOfRuin = math.Exp(-2 * mean * bankroll / variance)
+	}
+
+	if elapsed > 0 {
+		result.HandsPerSecond = rounds / elapsed.Seconds()
+	}
+	return result
+}
+
+func RunBlackjackSimulation(settings SimulationBody, progress func(SimulationResult)) SimulationResult {
+	startedAt := time.Now()
+	rules := *settings.Rules
+
+	// The simulation plays on its own shoe so it never touches the shared registry
+	shoe := NewShoeWithSource(rules.Decks, NewSeededSource(*settings.Seed))
+	shoe.Penetration = settings.Penetration
+
+	hiLo := CountingSystems["HI_LO"]
+	runningCount := 0
+	countedCards := 0
+
+	var stats simulationStats
+	for stats.rounds < settings.Hands {
+		// The audit log is not needed here and would grow with every shuffle
+		if len(shoe.Shuffles) > 0 {
+			shoe.Shuffles = nil
+			runningCount = 0
+			countedCards = 0
+		}
+		for ; countedCards < shoe.NextCard; countedCards++ {
+			runningCount += hiLo.Tags[shoe.Cards[countedCards].RankLabel[0]]
+		}
+		trueCount := float64(runningCount) / (float64(shoe.CardsLeft()) / 52)
+
+		bet := BetForTrueCount(settings.BetSpread, trueCount)
+		round := NewBlackjackRound(shoe, bet, rules)
+		for round.Status != RoundStatusSettled {
+			if round.Status == RoundStatusInsurance {
+				takeInsurance, _ := CalculateInsuranceDecision(&trueCount, nil)
+				if takeInsurance {
+					round.Act("INSURANCE")
+				} else {
+					round.Act("DECLINE_INSURANCE")
+				}
+				continue
+			}
+			if err := round.Act(SimulationDecision(round)); err != nil {
+				round.Act("STAND")
+			}
+		}
+		stats.Record(bet, round.NetResult)
+
+		if progress != nil && stats.rounds%simulationProgressInterval == 0 {
+			progress(stats.Result(settings.Bankroll, time.Since(startedAt)))
+		}
+	}
+
+	return stats.Result(settings.Bankroll, time.Since(startedAt))
+}
+
+func StartSimulation(settings SimulationBody) *SimulationJob {
+	job := &SimulationJob{
+		ID:        NewRandomID(),
+		Status:    SimulationStatusRunning,
+		Settings:  settings,
+		StartedAt: time.Now(),
+	}
+	Simulations[job.ID] = job
+
+	go func() {
+		result := RunBlackjackSimulation(settings, func(partial SimulationResult) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			job.Result = partial
+		})
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		job.Result = result
+		job.Status = SimulationStatusCompleted
+	}()
+
+	return job
+}
+
+func MakeSimulationResponseBody(job *SimulationJob) SimulationResponseBody {
+	return SimulationResponseBody{
+		ID:          job.ID,
+		Status:      job.Status,
+		Hands:       job.Settings.Hands,
+		Progress:    float64(job.Result.HandsPlayed) / float64(job.Settings.Hands),
+		Rules:       *job.Settings.Rules,
+		Penetration: job.Settings.Penetration,
+		BetSpread:   job.Settings.BetSpread,
+		Bankroll:    job.Settings.Bankroll,
+		Seed:        *job.Settings.Seed,
+		StartedAt:   job.StartedAt,
+		Result:      job.Result,
+	}
+}
+
+// Handlers
+
+func CreateBlackjackSimulationHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var simulationBody SimulationBody
+	err := json.NewDecoder(r.Body).Decode(&simulationBody)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	validationErr := ValidateSimulationBody(&simulationBody)
+	if validationErr != nil {
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	job := StartSimulation(simulationBody)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(MakeSimulationResponseBody(job))
+}
+
+func GetSimulationHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	simulationID := vars["id"]
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	job, found := Simulations[simulationID]
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	json.NewEncoder(w).Encode(MakeSimulationResponseBody(job))
+}
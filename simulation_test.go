@@ -0,0 +1,100 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBlackjackSimulation(t *testing.T) {
+	seed := int64(42)
+	settings := SimulationBody{Hands: 20000, Seed: &seed}
+	if err := ValidateSimulationBody(&settings); err != nil {
+		t.Fatalf("default simulation settings expected to be valid")
+	}
+
+	updates := 0
+	result := RunBlackjackSimulation(settings, func(SimulationResult) { updates++ })
+	if result.HandsPlayed != 20000 || updates != 2 {
+		t.Errorf("simulation expected to play 20000 hands with two progress updates")
+	}
+	if result.TotalWagered != 20000 {
+		t.Errorf("flat betting expected to wager one unit per hand")
+	}
+	if math.Abs(result.WinRate+result.LossRate+result.PushRate-1) > 1e-9 {
+		t.Errorf("win, loss and push rates expected to sum to 1")
+	}
+	if result.StandardDeviation < 1 || result.StandardDeviation > 1.3 {
+		t.Errorf("standard deviation per hand expected to be near 1.15, got %f", result.StandardDeviation)
+	}
+	if result.RiskOfRuin <= 0 || result.RiskOfRuin > 1 {
+		t.Errorf("risk of ruin expected to be a probability")
+	}
+
+	repeated := RunBlackjackSimulation(settings, nil)
+	if repeated.NetResult != result.NetResult {
+		t.Errorf("seeded simulations expected to be reproducible")
+	}
+}
+
+func TestSimulationHouseEdge(t *testing.T) {
+	// Six decks, S17, DAS and late surrender give up about 0.36% to flat basic strategy
+	seed := int64(7)
+	settings := SimulationBody{Hands: 300000, Seed: &seed}
+	ValidateSimulationBody(&settings)
+	result := RunBlackjackSimulation(settings, nil)
+	if result.HouseEdge < 0.002 || result.HouseEdge > 0.006 {
+		t.Errorf("basic strategy house edge expected to be near 0.36%%, got %f", result.HouseEdge)
+	}
+}
+
+func TestSimulationSettings(t *testing.T) {
+	settings := SimulationBody{BetSpread: []BetSpreadStep{{TrueCount: 3, Units: 8}, {TrueCount: 1, Units: 1}, {TrueCount: 2, Units: 4}}}
+	if err := ValidateSimulationBody(&settings); err != nil {
+		t.Errorf("bet spread expected to be valid")
+	}
+	if settings.Hands != DefaultSimulationHands || settings.Penetration != DefaultPenetration || settings.Seed == nil {
+		t.Errorf("simulation defaults expected to be filled in")
+	}
+
+	if BetForTrueCount(settings.BetSpread, -2) != 1 {
+		t.Errorf("negative counts expected to bet the minimum")
+	}
+	if BetForTrueCount(settings.BetSpread, 2.5) != 4 {
+		t.Errorf("true count 2.5 expected to bet 4 units")
+	}
+	if BetForTrueCount(settings.BetSpread, 6) != 8 {
+		t.Errorf("high counts expected to bet the maximum")
+	}
+
+	if ValidateSimulationBody(&SimulationBody{BetSpread: []BetSpreadStep{{Units: 0}}}) == nil {
+		t.Errorf("zero unit bets expected to be rejected")
+	}
+	if ValidateSimulationBody(&SimulationBody{Hands: MaxSimulationHands + 1}) == nil {
+		t.Errorf("too many hands expected to be rejected")
+	}
+}
+
+func TestSimulationDecisions(t *testing.T) {
+	rules := DefaultBlackjackRules()
+	rules.DoubleAfterSplit = false
+	round := &BlackjackRound{Rules: rules, PlayerHands: []*BlackjackHand{{Cards: []string{"A", "7"}, FromSplit: true}, {Cards: []string{"A", "9"}, FromSplit: true}}, DealerCards: []string{"4S", "TS"}}
+	if SimulationDecision(round) != "STAND" {
+		t.Errorf("soft 18 after a split without DAS expected to stand")
+	}
+
+	round = &BlackjackRound{Rules: DefaultBlackjackRules(), PlayerHands: []*BlackjackHand{{Cards: []string{"T", "6", "A"}}}, DealerCards: []string{"7S", "TS"}}
+	if SimulationDecision(round) != "STAND" {
+		t.Errorf("hard 17 with an ace vs 7 expected to stand")
+	}
+	round.PlayerHands[0].Cards = []string{"6", "5", "A"}
+	round.DealerCards = []string{"4S", "TS"}
+	if SimulationDecision(round) != "STAND" {
+		t.Errorf("hard 12 with an ace vs 4 expected to stand")
+	}
+
+	round.Rules = rules
+	round.PlayerHands = []*BlackjackHand{{Cards: []string{"8", "3"}}, {Cards: []string{"8", "T"}}}
+	if SimulationDecision(round) != "HIT" {
+		t.Errorf("11 after a split without DAS expected to hit")
+	}
+}